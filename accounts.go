@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ── Account lists ────────────────────────────────────────────────────────────
+//
+// The accounts a batch of passwords is for, one per CSV row. The header row
+// names the columns, in any order; only title is needed. passgen batch also
+// reads how to generate each row's password.
+
+// Account describes who or what one generated password is for.
+type Account struct {
+	Title    string
+	Username string
+	URL      string
+	Notes    string
+
+	// passgen batch only: the row's own -type, -profile and -policy
+	Type    string
+	Profile string
+	Policy  string
+
+	Line int // in the CSV file
+}
+
+// accountColumns maps each accepted header, lower-cased, to its field.
+var accountColumns = map[string]func(*Account) *string{
+	"title":    func(a *Account) *string { return &a.Title },
+	"name":     func(a *Account) *string { return &a.Title },
+	"username": func(a *Account) *string { return &a.Username },
+	"login":    func(a *Account) *string { return &a.Username },
+	"url":      func(a *Account) *string { return &a.URL },
+	"notes":    func(a *Account) *string { return &a.Notes },
+	"type":     func(a *Account) *string { return &a.Type },
+	"profile":  func(a *Account) *string { return &a.Profile },
+	"policy":   func(a *Account) *string { return &a.Policy },
+}
+
+// readAccounts reads the accounts listed in a CSV file. With batch, rows may
+// also choose their generator, and an account can be named by its username
+// alone; rows naming none are left for the caller to skip.
+func readAccounts(path string, batch bool) ([]Account, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.TrimLeadingSpace = true
+	header, err := r.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("%s is empty — it needs a header row, like title,username,url,notes", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	known := "title, username, url and notes"
+	if batch {
+		known = "title, username, url, notes, type, profile and policy"
+	}
+	fields := make([]func(*Account) *string, len(header))
+	hasTitle, hasUser := false, false
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		fields[i] = accountColumns[name]
+		if fields[i] == nil || !batch && (name == "type" || name == "profile" || name == "policy") {
+			return nil, fmt.Errorf("%s: unknown column %q — use %s", path, name, known)
+		}
+		hasTitle = hasTitle || name == "title" || name == "name"
+		hasUser = hasUser || name == "username" || name == "login"
+	}
+	switch {
+	case batch && !hasTitle && !hasUser:
+		return nil, fmt.Errorf("%s needs a title or username column", path)
+	case !batch && !hasTitle:
+		return nil, fmt.Errorf("%s needs a title column", path)
+	}
+
+	var accounts []Account
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		var a Account
+		a.Line, _ = r.FieldPos(0)
+		for i, v := range row {
+			*fields[i](&a) = strings.TrimSpace(v)
+		}
+		if a.Title == "" && !batch {
+			return nil, fmt.Errorf("%s, line %d: no title", path, a.Line)
+		}
+		accounts = append(accounts, a)
+	}
+	if len(accounts) == 0 {
+		return nil, fmt.Errorf("%s lists no accounts", path)
+	}
+	return accounts, nil
+}
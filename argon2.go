@@ -0,0 +1,309 @@
+package main
+
+import (
+	"encoding/binary"
+	"math/bits"
+	"sync"
+)
+
+// ── BLAKE2b (RFC 7693), unkeyed ──────────────────────────────────────────────
+
+var blake2bIV = [8]uint64{
+	0x6a09e667f3bcc908, 0xbb67ae8584caa73b, 0x3c6ef372fe94f82b, 0xa54ff53a5f1d36f1,
+	0x510e527fade682d1, 0x9b05688c2b3e6c1f, 0x1f83d9abfb41bd6b, 0x5be0cd19137e2179,
+}
+
+var blake2bSigma = [12][16]byte{
+	{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+	{14, 10, 4, 8, 9, 15, 13, 6, 1, 12, 0, 2, 11, 7, 5, 3},
+	{11, 8, 12, 0, 5, 2, 15, 13, 10, 14, 3, 6, 7, 1, 9, 4},
+	{7, 9, 3, 1, 13, 12, 11, 14, 2, 6, 5, 10, 4, 0, 15, 8},
+	{9, 0, 5, 7, 2, 4, 10, 15, 14, 1, 11, 12, 6, 8, 3, 13},
+	{2, 12, 6, 10, 0, 11, 8, 3, 4, 13, 7, 5, 15, 14, 1, 9},
+	{12, 5, 1, 15, 14, 13, 4, 10, 0, 7, 6, 3, 9, 2, 8, 11},
+	{13, 11, 7, 14, 12, 1, 3, 9, 5, 0, 15, 4, 8, 6, 2, 10},
+	{6, 15, 14, 9, 11, 3, 0, 8, 12, 2, 13, 7, 1, 4, 10, 5},
+	{10, 2, 8, 4, 7, 6, 1, 5, 15, 11, 9, 14, 3, 12, 13, 0},
+	{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+	{14, 10, 4, 8, 9, 15, 13, 6, 1, 12, 0, 2, 11, 7, 5, 3},
+}
+
+// blake2b returns the size-byte (1–64) BLAKE2b hash of data.
+func blake2b(data []byte, size int) []byte {
+	h := blake2bIV
+	h[0] ^= 0x01010000 ^ uint64(size)
+
+	var block [128]byte
+	var t uint64
+	for len(data) > 128 {
+		copy(block[:], data)
+		t += 128
+		blake2bCompress(&h, &block, t, false)
+		data = data[128:]
+	}
+	block = [128]byte{}
+	copy(block[:], data)
+	t += uint64(len(data))
+	blake2bCompress(&h, &block, t, true)
+
+	var out [64]byte
+	for i, v := range h {
+		binary.LittleEndian.PutUint64(out[i*8:], v)
+	}
+	return out[:size]
+}
+
+func blake2bCompress(h *[8]uint64, block *[128]byte, t uint64, last bool) {
+	var m [16]uint64
+	for i := range m {
+		m[i] = binary.LittleEndian.Uint64(block[i*8:])
+	}
+	var v [16]uint64
+	copy(v[:8], h[:])
+	copy(v[8:], blake2bIV[:])
+	v[12] ^= t
+	if last {
+		v[14] = ^v[14]
+	}
+	g := func(a, b, c, d int, x, y uint64) {
+		v[a] += v[b] + x
+		v[d] = bits.RotateLeft64(v[d]^v[a], -32)
+		v[c] += v[d]
+		v[b] = bits.RotateLeft64(v[b]^v[c], -24)
+		v[a] += v[b] + y
+		v[d] = bits.RotateLeft64(v[d]^v[a], -16)
+		v[c] += v[d]
+		v[b] = bits.RotateLeft64(v[b]^v[c], -63)
+	}
+	for _, s := range blake2bSigma {
+		g(0, 4, 8, 12, m[s[0]], m[s[1]])
+		g(1, 5, 9, 13, m[s[2]], m[s[3]])
+		g(2, 6, 10, 14, m[s[4]], m[s[5]])
+		g(3, 7, 11, 15, m[s[6]], m[s[7]])
+		g(0, 5, 10, 15, m[s[8]], m[s[9]])
+		g(1, 6, 11, 12, m[s[10]], m[s[11]])
+		g(2, 7, 8, 13, m[s[12]], m[s[13]])
+		g(3, 4, 9, 14, m[s[14]], m[s[15]])
+	}
+	for i := range h {
+		h[i] ^= v[i] ^ v[i+8]
+	}
+}
+
+// ── Argon2id (RFC 9106) ──────────────────────────────────────────────────────
+
+const (
+	argon2Version    = 0x13
+	argon2id         = 2
+	argon2SyncPoints = 4
+	argon2QWords     = 128 // uint64s per 1 KiB block
+)
+
+type argon2Block [argon2QWords]uint64
+
+// argon2idKey hashes password with salt using time passes over memory KiB
+// split into lanes, returning keyLen bytes.
+func argon2idKey(password, salt []byte, time, memory, lanes, keyLen uint32) []byte {
+	// H0 over the parameters and every input, each prefixed by its length;
+	// the optional secret and associated data are empty
+	var in []byte
+	for _, v := range []uint32{lanes, keyLen, memory, time, argon2Version, argon2id} {
+		in = binary.LittleEndian.AppendUint32(in, v)
+	}
+	for _, b := range [][]byte{password, salt, nil, nil} {
+		in = binary.LittleEndian.AppendUint32(in, uint32(len(b)))
+		in = append(in, b...)
+	}
+	h0 := append(blake2b(in, 64), make([]byte, 8)...)
+
+	memory = memory / (argon2SyncPoints * lanes) * (argon2SyncPoints * lanes)
+	if memory < 2*argon2SyncPoints*lanes {
+		memory = 2 * argon2SyncPoints * lanes
+	}
+	laneLen := memory / lanes
+	B := make([]argon2Block, memory)
+
+	// The first two blocks of each lane come straight from H0
+	var raw [1024]byte
+	for lane := uint32(0); lane < lanes; lane++ {
+		binary.LittleEndian.PutUint32(h0[68:], lane)
+		for i := uint32(0); i < 2; i++ {
+			binary.LittleEndian.PutUint32(h0[64:], i)
+			blake2bLong(raw[:], h0)
+			for j := range B[lane*laneLen+i] {
+				B[lane*laneLen+i][j] = binary.LittleEndian.Uint64(raw[j*8:])
+			}
+		}
+	}
+
+	segLen := laneLen / argon2SyncPoints
+	for pass := uint32(0); pass < time; pass++ {
+		for slice := uint32(0); slice < argon2SyncPoints; slice++ {
+			var wg sync.WaitGroup
+			for lane := uint32(0); lane < lanes; lane++ {
+				wg.Add(1)
+				go func(lane uint32) {
+					defer wg.Done()
+					argon2Segment(B, pass, slice, lane, lanes, laneLen, segLen, memory, time)
+				}(lane)
+			}
+			wg.Wait()
+		}
+	}
+
+	// XOR the last block of every lane and stretch it to keyLen
+	final := B[laneLen-1]
+	for lane := uint32(1); lane < lanes; lane++ {
+		for j, v := range B[lane*laneLen+laneLen-1] {
+			final[j] ^= v
+		}
+	}
+	for j, v := range final {
+		binary.LittleEndian.PutUint64(raw[j*8:], v)
+	}
+	key := make([]byte, keyLen)
+	blake2bLong(key, raw[:])
+	return key
+}
+
+// argon2Segment fills one lane's blocks for one slice of one pass. The
+// first half of the first pass picks reference blocks independently of
+// the data (as Argon2i does); the rest use the previous block (as Argon2d).
+func argon2Segment(B []argon2Block, pass, slice, lane, lanes, laneLen, segLen, memory, time uint32) {
+	independent := pass == 0 && slice < argon2SyncPoints/2
+	var addresses, in, zero argon2Block
+	nextAddresses := func() {
+		in[6]++
+		argon2Compress(&addresses, &in, &zero, false)
+		argon2Compress(&addresses, &addresses, &zero, false)
+	}
+	if independent {
+		in[0], in[1], in[2], in[3], in[4], in[5] = uint64(pass), uint64(lane), uint64(slice), uint64(memory), uint64(time), argon2id
+	}
+
+	index := uint32(0)
+	if pass == 0 && slice == 0 {
+		index = 2 // filled from H0
+		if independent {
+			nextAddresses()
+		}
+	}
+	offset := lane*laneLen + slice*segLen + index
+	for ; index < segLen; index, offset = index+1, offset+1 {
+		prev := offset - 1
+		if index == 0 && slice == 0 {
+			prev += laneLen // wrap to the lane's last block
+		}
+		var rand uint64
+		if independent {
+			if index%argon2QWords == 0 {
+				nextAddresses()
+			}
+			rand = addresses[index%argon2QWords]
+		} else {
+			rand = B[prev][0]
+		}
+
+		// Pick the reference lane and the window of blocks it may use
+		refLane := uint32(rand>>32) % lanes
+		if pass == 0 && slice == 0 {
+			refLane = lane
+		}
+		area, start := 3*segLen, ((slice+1)%argon2SyncPoints)*segLen
+		if lane == refLane {
+			area += index
+		}
+		if pass == 0 {
+			area, start = slice*segLen, 0
+			if slice == 0 || lane == refLane {
+				area += index
+			}
+		}
+		if index == 0 || lane == refLane {
+			area--
+		}
+		p := rand & 0xffffffff
+		p = p * p >> 32
+		p = p * uint64(area) >> 32
+		ref := refLane*laneLen + uint32((uint64(start)+uint64(area)-(p+1))%uint64(laneLen))
+
+		argon2Compress(&B[offset], &B[prev], &B[ref], true)
+	}
+}
+
+// argon2Compress is the compression function G: out = P(x) ^ x for
+// x = a ^ b, XORed into out's old value when xor is set (version 0x13).
+func argon2Compress(out, a, b *argon2Block, xor bool) {
+	var x, t argon2Block
+	for i := range x {
+		x[i] = a[i] ^ b[i]
+	}
+	t = x
+	var idx [16]int
+	for row := 0; row < 8; row++ {
+		for i := range idx {
+			idx[i] = row*16 + i
+		}
+		blamka(&t, &idx)
+	}
+	for col := 0; col < 8; col++ {
+		for i := 0; i < 8; i++ {
+			idx[2*i] = i*16 + 2*col
+			idx[2*i+1] = i*16 + 2*col + 1
+		}
+		blamka(&t, &idx)
+	}
+	for i := range t {
+		if xor {
+			out[i] ^= t[i] ^ x[i]
+		} else {
+			out[i] = t[i] ^ x[i]
+		}
+	}
+}
+
+// blamka applies the BLAKE2b round, with multiplications, to 16 words of t.
+func blamka(t *argon2Block, idx *[16]int) {
+	g := func(a, b, c, d int) {
+		a, b, c, d = idx[a], idx[b], idx[c], idx[d]
+		mul := func(x, y uint64) uint64 { return 2 * uint64(uint32(x)) * uint64(uint32(y)) }
+		t[a] += t[b] + mul(t[a], t[b])
+		t[d] = bits.RotateLeft64(t[d]^t[a], -32)
+		t[c] += t[d] + mul(t[c], t[d])
+		t[b] = bits.RotateLeft64(t[b]^t[c], -24)
+		t[a] += t[b] + mul(t[a], t[b])
+		t[d] = bits.RotateLeft64(t[d]^t[a], -16)
+		t[c] += t[d] + mul(t[c], t[d])
+		t[b] = bits.RotateLeft64(t[b]^t[c], -63)
+	}
+	g(0, 4, 8, 12)
+	g(1, 5, 9, 13)
+	g(2, 6, 10, 14)
+	g(3, 7, 11, 15)
+	g(0, 5, 10, 15)
+	g(1, 6, 11, 12)
+	g(2, 7, 8, 13)
+	g(3, 4, 9, 14)
+}
+
+// blake2bLong is Argon2's variable-length hash H', filling out.
+func blake2bLong(out, in []byte) {
+	msg := binary.LittleEndian.AppendUint32(nil, uint32(len(out)))
+	msg = append(msg, in...)
+	if len(out) <= 64 {
+		copy(out, blake2b(msg, len(out)))
+		return
+	}
+	// Emit the first half of each 64-byte link in the chain; the last link
+	// is hashed to exactly the bytes that remain
+	v := blake2b(msg, 64)
+	for {
+		copy(out, v[:32])
+		out = out[32:]
+		if len(out) <= 64 {
+			copy(out, blake2b(v, len(out)))
+			return
+		}
+		v = blake2b(v, 64)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"strings"
+)
+
+// ── Batch provisioning ───────────────────────────────────────────────────────
+//
+// passgen batch makes a password for every account in a CSV file, using the
+// same flags as a single password but letting each row choose its own type,
+// profile and policy, and writes the credentials to a file only the owner
+// can read. A row that can't be generated is skipped and reported instead
+// of stopping the batch.
+
+// batchCredential is one row of passgen batch output.
+type batchCredential struct {
+	Title    string `json:"title,omitempty"`
+	Username string `json:"username,omitempty"`
+	URL      string `json:"url,omitempty"`
+	Notes    string `json:"notes,omitempty"`
+	Password string `json:"password"`
+	Hash     string `json:"hash,omitempty"`
+}
+
+// writePrivateFile writes data to a new file only its owner can read,
+// replacing an existing one only with force.
+func writePrivateFile(path string, data []byte, force bool) error {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if force {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	f, err := os.OpenFile(path, flags, 0o600)
+	if err != nil {
+		return err
+	}
+	// A replaced file keeps its old mode otherwise
+	err = f.Chmod(0o600)
+	if err == nil {
+		_, err = f.Write(data)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// writeBatch writes the credentials to path as csv or json, with a hash
+// column when hashed.
+func writeBatch(path, format string, force bool, creds []batchCredential, hashed bool) error {
+	var buf bytes.Buffer
+	if format == "json" {
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(creds); err != nil {
+			return err
+		}
+	} else {
+		cw := csv.NewWriter(&buf)
+		header := []string{"title", "username", "url", "notes", "password"}
+		if hashed {
+			header = append(header, "hash")
+		}
+		cw.Write(header)
+		for _, c := range creds {
+			// The password and hash are written exactly as generated
+			row := []string{csvCell(c.Title), csvCell(c.Username), csvCell(c.URL), csvCell(c.Notes), c.Password}
+			if hashed {
+				row = append(row, c.Hash)
+			}
+			cw.Write(row)
+		}
+		cw.Flush()
+		if err := cw.Error(); err != nil {
+			return err
+		}
+	}
+	return writePrivateFile(path, buf.Bytes(), force)
+}
+
+// csvCell keeps a spreadsheet from reading an account cell as a formula: one
+// that starts with =, +, - or @ gets a leading '. Passwords are left alone, as
+// a ' added to one would be taken for part of it.
+func csvCell(s string) string {
+	if s != "" && strings.ContainsRune("=+-@", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
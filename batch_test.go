@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCSVCell(t *testing.T) {
+	for in, want := range map[string]string{
+		"=HYPERLINK(\"x\")": "'=HYPERLINK(\"x\")",
+		"+1":                "'+1",
+		"-x7Q":              "'-x7Q",
+		"@SUM(A1)":          "'@SUM(A1)",
+		"a=b":               "a=b",
+		"":                  "",
+	} {
+		if got := csvCell(in); got != want {
+			t.Errorf("csvCell(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+// batchFiles writes accounts to an -in file and returns it with an -out
+// path next to it.
+func batchFiles(t *testing.T, accounts string) (in, out string) {
+	t.Helper()
+	dir := t.TempDir()
+	in = filepath.Join(dir, "accounts.csv")
+	if err := os.WriteFile(in, []byte(accounts), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return in, filepath.Join(dir, "credentials")
+}
+
+// readBatchCSV runs passgen batch and returns each title's password.
+func readBatchCSV(t *testing.T, accounts string, args ...string) map[string]string {
+	in, out := batchFiles(t, accounts)
+	args = append([]string{"batch", "-in", in, "-out", out}, args...)
+	if _, errOut, err := runPassgen(t, "", args...); err != nil {
+		t.Fatalf("passgen %s: %v\n%s", strings.Join(args, " "), err, errOut)
+	}
+	info, err := os.Stat(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("-out file has mode %#o, want 0600", perm)
+	}
+	f, err := os.Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pw := map[string]string{}
+	for _, r := range rows[1:] {
+		pw[r[0]] = r[4]
+	}
+	return pw
+}
+
+func TestBatchCSVEscaping(t *testing.T) {
+	// Every password starts with a formula character; only the title may be
+	// escaped
+	pw := readBatchCSV(t, `title,username
+=cmd,alice
+Plain,bob
+`, "-charset", "=+-@", "-length", "12")
+	if len(pw) != 2 {
+		t.Fatalf("wrote %v, want 2 rows", pw)
+	}
+	for _, title := range []string{"'=cmd", "Plain"} {
+		p, ok := pw[title]
+		if !ok {
+			t.Errorf("no row titled %q in %v", title, pw)
+		}
+		if len(p) != 12 || strings.Trim(p, "=+-@") != "" {
+			t.Errorf("%s: password cell %q isn't the password as generated", title, p)
+		}
+	}
+}
+
+func TestBatchRowTypes(t *testing.T) {
+	pw := readBatchCSV(t, `title,username,type,profile,policy
+Alice,alice,,,
+Wallet,w,bip39,,
+App,a,framework,django,
+Poem,p,sentence,,
+Words,m,markov,,
+Bank,b,random,,"minlength: 20; maxlength: 20; required: digit; allowed: upper"
+`)
+	if len(pw) != 6 {
+		t.Fatalf("wrote %d rows, want 6", len(pw))
+	}
+	if n := len(strings.Fields(pw["Wallet"])); n != 12 {
+		t.Errorf("bip39 row has %d words, want 12", n)
+	}
+	if n := len(pw["App"]); n != 50 {
+		t.Errorf("django row is %d characters, want 50", n)
+	}
+	if pw["Poem"] == "" || pw["Words"] == "" {
+		t.Errorf("sentence or markov row is empty")
+	}
+	if p := pw["Bank"]; len(p) != 20 || strings.ContainsAny(p, "abcdefghijklmnopqrstuvwxyz") {
+		t.Errorf("policy row %q doesn't follow its policy", p)
+	}
+}
+
+func TestBatchFlags(t *testing.T) {
+	pw := readBatchCSV(t, `title,username,type,profile
+Alice,alice,,
+Bob,bob,,safe
+Segments,s,segment,
+`, "-length", "30", "-no-digits", "-exclude", "aeiou", "-no-repeat")
+	for name, p := range pw {
+		if strings.ContainsAny(p, "aeiou0123456789") {
+			t.Errorf("%s: %q ignores -exclude or -no-digits", name, p)
+		}
+		for i := 1; i < len(p); i++ {
+			if p[i] == p[i-1] {
+				t.Errorf("%s: %q ignores -no-repeat", name, p)
+			}
+		}
+	}
+	if len(pw["Alice"]) != 30 {
+		t.Errorf("%q ignores -length", pw["Alice"])
+	}
+	if p := strings.Trim(pw["Bob"], "ABCDEFGHIJKLMNOPQRSTUVWXYZbcdfghjklmnpqrstvwxyz-_."); p != "" {
+		t.Errorf("profile safe row has symbols %q", p)
+	}
+	if strings.Count(pw["Segments"], "-") != 2 {
+		t.Errorf("segment row %q", pw["Segments"])
+	}
+}
+
+func TestBatchSkipsRows(t *testing.T) {
+	in, out := batchFiles(t, `title,username,type,profile
+Alice,alice,,
+Bad,b,phrase,
+,,,
+Odd,o,random,xyz
+`)
+	_, errOut, err := runPassgen(t, "", "batch", "-in", in, "-out", out, "-format", "json", "-no-repeat")
+	if err == nil {
+		t.Fatal("passgen batch exited 0 with rows skipped")
+	}
+	for _, want := range []string{
+		"Wrote 1 of 4 accounts",
+		"line 3 (Bad): character constraints apply to random and segment modes only",
+		"line 4: no title or username",
+		"line 5 (Odd): -symbols must not contain letters or digits",
+	} {
+		if !strings.Contains(errOut, want) {
+			t.Errorf("passgen batch said\n%s\nwant %q", errOut, want)
+		}
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var creds []batchCredential
+	if err := json.Unmarshal(data, &creds); err != nil {
+		t.Fatal(err)
+	}
+	if len(creds) != 1 || creds[0].Title != "Alice" {
+		t.Errorf("wrote %+v, want Alice only", creds)
+	}
+}
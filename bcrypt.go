@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+)
+
+// ── bcrypt ($2b$) ────────────────────────────────────────────────────────────
+//
+// Blowfish with the expensive key schedule: the password and salt are mixed
+// into the subkeys 2^cost times, and the result encrypts a fixed text.
+
+// bcryptEncoding is bcrypt's own base64 alphabet, unpadded.
+var bcryptEncoding = base64.NewEncoding("./ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789").WithPadding(base64.NoPadding)
+
+const bcryptMaxPassword = 72 // bytes; bcrypt ignores anything after
+
+type blowfish struct {
+	p [18]uint32
+	s [4][256]uint32
+}
+
+func (b *blowfish) f(x uint32) uint32 {
+	return ((b.s[0][x>>24] + b.s[1][x>>16&0xff]) ^ b.s[2][x>>8&0xff]) + b.s[3][x&0xff]
+}
+
+func (b *blowfish) encrypt(l, r uint32) (uint32, uint32) {
+	for i := 0; i < 16; i += 2 {
+		l ^= b.p[i]
+		r ^= b.f(l)
+		r ^= b.p[i+1]
+		l ^= b.f(r)
+	}
+	return r ^ b.p[17], l ^ b.p[16]
+}
+
+// nextWord reads 4 bytes of data big-endian from *pos, wrapping around.
+func nextWord(data []byte, pos *int) uint32 {
+	var w uint32
+	for i := 0; i < 4; i++ {
+		w = w<<8 | uint32(data[*pos])
+		*pos = (*pos + 1) % len(data)
+	}
+	return w
+}
+
+// expandKey XORs key into the P-array, then re-encrypts every subkey in
+// turn, mixing salt into each block first when there is one.
+func (b *blowfish) expandKey(key, salt []byte) {
+	pos := 0
+	for i := range b.p {
+		b.p[i] ^= nextWord(key, &pos)
+	}
+	var l, r uint32
+	saltPos := 0
+	next := func() (uint32, uint32) {
+		if salt != nil {
+			l ^= nextWord(salt, &saltPos)
+			r ^= nextWord(salt, &saltPos)
+		}
+		l, r = b.encrypt(l, r)
+		return l, r
+	}
+	for i := 0; i < len(b.p); i += 2 {
+		b.p[i], b.p[i+1] = next()
+	}
+	for k := range b.s {
+		for i := 0; i < 256; i += 2 {
+			b.s[k][i], b.s[k][i+1] = next()
+		}
+	}
+}
+
+// bcryptHash returns the $2b$ hash of password under a 16-byte salt.
+func bcryptHash(password, salt []byte, cost int) (string, error) {
+	if len(password) > bcryptMaxPassword {
+		return "", fmt.Errorf("bcrypt only uses the first %d bytes of a password — this one has %d", bcryptMaxPassword, len(password))
+	}
+	if cost < 4 || cost > 31 {
+		return "", fmt.Errorf("bcrypt cost must be 4–31, not %d", cost)
+	}
+	key := append(append([]byte{}, password...), 0)
+
+	b := &blowfish{p: blowfishP, s: blowfishS}
+	b.expandKey(key, salt)
+	for i := uint64(0); i < 1<<cost; i++ {
+		b.expandKey(key, nil)
+		b.expandKey(salt, nil)
+	}
+
+	text := []byte("OrpheanBeholderScryDoubt")
+	for i := 0; i < len(text); i += 8 {
+		l, r := binary.BigEndian.Uint32(text[i:]), binary.BigEndian.Uint32(text[i+4:])
+		for j := 0; j < 64; j++ {
+			l, r = b.encrypt(l, r)
+		}
+		binary.BigEndian.PutUint32(text[i:], l)
+		binary.BigEndian.PutUint32(text[i+4:], r)
+	}
+
+	// The last byte of the ciphertext is dropped, as every bcrypt does
+	cs := strconv.Itoa(cost)
+	if cost < 10 {
+		cs = "0" + cs
+	}
+	return "$2b$" + cs + "$" + bcryptEncoding.EncodeToString(salt) + bcryptEncoding.EncodeToString(text[:23]), nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ── BIP39 mnemonics ──────────────────────────────────────────────────────────
+//
+// A mnemonic encodes 128–256 bits of entropy followed by the first ENT/32
+// bits of its SHA-256, 11 bits per word.
+
+// bip39Index maps each word to its position in bip39Words.
+var bip39Index = func() map[string]int {
+	idx := make(map[string]int, len(bip39Words))
+	for i, w := range bip39Words {
+		idx[w] = i
+	}
+	return idx
+}()
+
+// bip39EntropyBytes returns how many bytes of entropy a mnemonic of the
+// given length encodes: 12 words carry 128 bits, each 3 more add 32.
+func bip39EntropyBytes(words int) (int, error) {
+	switch words {
+	case 12, 15, 18, 21, 24:
+		return words / 3 * 4, nil
+	}
+	return 0, fmt.Errorf("a BIP39 mnemonic has 12, 15, 18, 21 or 24 words, not %d", words)
+}
+
+// bip39Mnemonic encodes entropy (16–32 bytes, a multiple of 4) as words.
+func bip39Mnemonic(entropy []byte) (string, error) {
+	if len(entropy) < 16 || len(entropy) > 32 || len(entropy)%4 != 0 {
+		return "", fmt.Errorf("BIP39 entropy must be 16–32 bytes in steps of 4, not %d", len(entropy))
+	}
+	sum := sha256.Sum256(entropy)
+	data := append(append([]byte{}, entropy...), sum[0]) // 8 checksum bits cover every size
+	bit := func(i int) int { return int(data[i/8]>>(7-i%8)) & 1 }
+
+	n := (len(entropy)*8 + len(entropy)/4) / 11
+	words := make([]string, n)
+	for w := range words {
+		idx := 0
+		for b := 0; b < 11; b++ {
+			idx = idx<<1 | bit(w*11+b)
+		}
+		words[w] = bip39Words[idx]
+	}
+	return strings.Join(words, " "), nil
+}
+
+// bip39Entropy decodes a mnemonic back to its entropy, checking every word
+// and the checksum.
+func bip39Entropy(mnemonic string) ([]byte, error) {
+	words := strings.Fields(strings.ToLower(mnemonic))
+	size, err := bip39EntropyBytes(len(words))
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, size+1)
+	for w, word := range words {
+		idx, ok := bip39Index[word]
+		if !ok {
+			return nil, fmt.Errorf("word %d, %q, is not in the BIP39 English list", w+1, word)
+		}
+		for b := 0; b < 11; b++ {
+			if idx>>(10-b)&1 == 1 {
+				i := w*11 + b
+				data[i/8] |= 1 << (7 - i%8)
+			}
+		}
+	}
+	entropy := data[:size]
+	sum := sha256.Sum256(entropy)
+	checkBits := uint(size / 4)
+	mask := byte(0xff) << (8 - checkBits)
+	if data[size]&mask != sum[0]&mask {
+		return nil, fmt.Errorf("checksum mismatch — a word is wrong or out of order")
+	}
+	return entropy, nil
+}
+
+func generateBIP39(words int) (string, error) {
+	size, err := bip39EntropyBytes(words)
+	if err != nil {
+		return "", err
+	}
+	entropy, err := randBytes(size)
+	if err != nil {
+		return "", err
+	}
+	return bip39Mnemonic(entropy)
+}
+
+// runBIP39 handles `passgen bip39 validate`, checking a mnemonic read from stdin.
+func runBIP39(args []string) {
+	if len(args) != 1 || args[0] != "validate" {
+		fmt.Fprintln(os.Stderr, "usage: passgen bip39 validate < mnemonic.txt")
+		os.Exit(2)
+	}
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	entropy, err := bip39Entropy(string(data))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("valid: %d words, %d bits of entropy\n", len(strings.Fields(string(data))), len(entropy)*8)
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// ── Character-level constraints ──────────────────────────────────────────────
+//
+// Enforced by rejection sampling: a password that breaks an adjacency rule is
+// thrown away and drawn again, so every valid password is equally likely and
+// the entropy is log2 of how many there are. Unique characters are drawn
+// without replacement instead.
+
+type Constraints struct {
+	MaxConsecutive  int  // longest run of one repeated character, 0 = unlimited
+	NoSequences     bool // no runs of three like abc, CBA, 123
+	NoKeyboardWalks bool // no runs of three adjacent keys like qwe, dsa, 789
+	UniqueChars     bool // no character used twice
+}
+
+func (c Constraints) active() bool {
+	return c.MaxConsecutive > 0 || c.NoSequences || c.NoKeyboardWalks || c.UniqueChars
+}
+
+// US QWERTY rows, unshifted and shifted; a shifted key shares its column.
+var keyboardRows = [][2]string{
+	{"`1234567890-=", "~!@#$%^&*()_+"},
+	{"qwertyuiop[]\\", "QWERTYUIOP{}|"},
+	{"asdfghjkl;'", "ASDFGHJKL:\""},
+	{"zxcvbnm,./", "ZXCVBNM<>?"},
+}
+
+var keyPositions = func() map[rune][2]int {
+	pos := make(map[rune][2]int)
+	for r, row := range keyboardRows {
+		for _, keys := range row {
+			for c, ch := range keys {
+				pos[ch] = [2]int{r, c}
+			}
+		}
+	}
+	return pos
+}()
+
+// isSequence reports whether a, b, c run up or down the alphabet or the
+// digits, ignoring case.
+func isSequence(a, b, c rune) bool {
+	a, b, c = unicode.ToLower(a), unicode.ToLower(b), unicode.ToLower(c)
+	inRun := func(lo, hi rune) bool {
+		return a >= lo && a <= hi && b >= lo && b <= hi && c >= lo && c <= hi
+	}
+	if !inRun('a', 'z') && !inRun('0', '9') {
+		return false
+	}
+	step := b - a
+	return (step == 1 || step == -1) && c-b == step
+}
+
+// isKeyboardWalk reports whether a, b, c are three neighbouring keys along
+// one QWERTY row, in either direction, ignoring shift.
+func isKeyboardWalk(a, b, c rune) bool {
+	pa, okA := keyPositions[a]
+	pb, okB := keyPositions[b]
+	pc, okC := keyPositions[c]
+	if !okA || !okB || !okC || pa[0] != pb[0] || pb[0] != pc[0] {
+		return false
+	}
+	step := pb[1] - pa[1]
+	return (step == 1 || step == -1) && pc[1]-pb[1] == step
+}
+
+// allows reports whether next may follow prev (the run so far, within one
+// segment) without breaking an adjacency constraint.
+func (c Constraints) allows(prev []rune, next rune) bool {
+	n := len(prev)
+	if c.MaxConsecutive > 0 && n >= c.MaxConsecutive {
+		run := 0
+		for i := n - 1; i >= 0 && prev[i] == next; i-- {
+			run++
+		}
+		if run >= c.MaxConsecutive {
+			return false
+		}
+	}
+	if n >= 2 {
+		if c.NoSequences && isSequence(prev[n-2], prev[n-1], next) {
+			return false
+		}
+		if c.NoKeyboardWalks && isKeyboardWalk(prev[n-2], prev[n-1], next) {
+			return false
+		}
+	}
+	return true
+}
+
+// fillSlots draws one character per slot from that slot's set, left to right,
+// and gives up (ok false) as soon as the password breaks an adjacency
+// constraint; callers retry until one passes. used tracks characters already
+// taken when UniqueChars is set, which later slots skip, and may be shared
+// across segments. ok is also false when a slot has no character left.
+func fillSlots(slots []string, c Constraints, used map[rune]bool) (out []rune, ok bool, err error) {
+	out = make([]rune, 0, len(slots))
+	candidates := make([]rune, 0, 96)
+	for _, set := range slots {
+		candidates = candidates[:0]
+		for _, ch := range set {
+			if !c.UniqueChars || !used[ch] {
+				candidates = append(candidates, ch)
+			}
+		}
+		if len(candidates) == 0 {
+			return nil, false, nil
+		}
+		idx, err := randInt(len(candidates))
+		if err != nil {
+			return nil, false, err
+		}
+		ch := candidates[idx]
+		if !c.allows(out, ch) {
+			return nil, false, nil
+		}
+		if c.UniqueChars {
+			used[ch] = true
+		}
+		out = append(out, ch)
+	}
+	return out, true, nil
+}
+
+// uniqueRunes returns the distinct characters of s in order of first appearance.
+func uniqueRunes(s string) []rune {
+	var out []rune
+	for _, ch := range s {
+		if !strings.ContainsRune(string(out), ch) {
+			out = append(out, ch)
+		}
+	}
+	return out
+}
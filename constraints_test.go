@@ -0,0 +1,80 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// countValid counts the length-n strings over charset that pass c by
+// trying every one.
+func countValid(charset []rune, n int, c Constraints) int {
+	count := 0
+	var walk func(prev []rune)
+	walk = func(prev []rune) {
+		if len(prev) == n {
+			count++
+			return
+		}
+		for _, ch := range charset {
+			if c.allows(prev, ch) {
+				walk(append(prev, ch))
+			}
+		}
+	}
+	walk(nil)
+	return count
+}
+
+func TestLog2Adjacent(t *testing.T) {
+	for _, tt := range []struct {
+		charset string
+		n       int
+		c       Constraints
+	}{
+		{"abc", 6, Constraints{MaxConsecutive: 1}},
+		{"ab", 8, Constraints{MaxConsecutive: 2}},
+		{"abcd", 6, Constraints{NoSequences: true}},
+		{"qwerty", 5, Constraints{NoKeyboardWalks: true, MaxConsecutive: 2}},
+		// é, € and ω can't be in a sequence or walk and share a state
+		{"abcé€ω", 5, Constraints{NoSequences: true, MaxConsecutive: 1}},
+		{"12é€", 6, Constraints{NoSequences: true, NoKeyboardWalks: true, MaxConsecutive: 2}},
+		{"é€ω", 6, Constraints{MaxConsecutive: 2}},
+	} {
+		chars := []rune(tt.charset)
+		want := math.Log2(float64(countValid(chars, tt.n, tt.c)))
+		if got := log2Adjacent(chars, tt.n, tt.c); math.Abs(got-want) > 1e-9 {
+			t.Errorf("log2Adjacent(%q, %d, %+v) = %v, want %v", tt.charset, tt.n, tt.c, got, want)
+		}
+	}
+}
+
+// The entropy is log2 of the number of valid passwords, which is only
+// right if each is drawn equally often.
+func TestConstraintsUniform(t *testing.T) {
+	cfg := RandomConfig{Length: 3, Charset: "abc", Constraints: Constraints{NoSequences: true}}
+	const draws = 25000 // 25 valid passwords: all but abc and cba
+	counts := make(map[string]int)
+	for i := 0; i < draws; i++ {
+		p, err := generateRandom(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts[p]++
+	}
+	if len(counts) != 25 || counts["abc"] > 0 || counts["cba"] > 0 {
+		t.Fatalf("drew %d distinct passwords, want 25 without abc and cba", len(counts))
+	}
+	for p, n := range counts {
+		// 1000 expected, standard deviation about 31
+		if n < 850 || n > 1150 {
+			t.Errorf("%s drawn %d times in %d, want about 1000", p, n, draws)
+		}
+	}
+}
+
+func TestConstraintsTooStrict(t *testing.T) {
+	_, _, err := randomEntropy(RandomConfig{Length: 16, Charset: "ab", Constraints: Constraints{MaxConsecutive: 1}})
+	if err == nil {
+		t.Error("2 valid passwords in 2^16 accepted for rejection sampling")
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha512"
+	"encoding/base64"
+	"hash"
+	"strconv"
+)
+
+// ── Unix crypt and LDAP schemes ──────────────────────────────────────────────
+//
+// sha512-crypt ($6$) for /etc/shadow, Apache's MD5 variant ($apr1$) for
+// htpasswd, and salted SHA-1 ({SSHA}) for OpenLDAP userPassword.
+
+// cryptAlphabet is the base64 alphabet of the crypt(3) family.
+const cryptAlphabet = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+var cryptEncoding = base64.NewEncoding(cryptAlphabet).WithPadding(base64.NoPadding)
+
+const sha512CryptDefaultRounds = 5000 // left out of the hash when used
+
+// cryptEncode writes digest in crypt's base64, taking bytes in the given
+// order in groups of three, each group least significant 6 bits first. A
+// shorter final group makes one character more than it has bytes.
+func cryptEncode(digest []byte, order []int) string {
+	var out []byte
+	for i := 0; i < len(order); i += 3 {
+		group := order[i:min(i+3, len(order))]
+		var w uint32
+		for _, j := range group {
+			w = w<<8 | uint32(digest[j])
+		}
+		for n := len(group) + 1; n > 0; n-- {
+			out = append(out, cryptAlphabet[w&0x3f])
+			w >>= 6
+		}
+	}
+	return string(out)
+}
+
+var sha512CryptOrder = []int{
+	0, 21, 42, 22, 43, 1, 44, 2, 23, 3, 24, 45, 25, 46, 4, 47, 5, 26, 6, 27, 48,
+	28, 49, 7, 50, 8, 29, 9, 30, 51, 31, 52, 10, 53, 11, 32, 12, 33, 54, 34, 55,
+	13, 56, 14, 35, 15, 36, 57, 37, 58, 16, 59, 17, 38, 18, 39, 60, 40, 61, 19,
+	62, 20, 41, 63,
+}
+
+var sha256CryptOrder = []int{
+	0, 10, 20, 21, 1, 11, 12, 22, 2, 3, 13, 23, 24, 4, 14, 15, 25, 5, 6, 16, 26,
+	27, 7, 17, 18, 28, 8, 9, 19, 29, 31, 30,
+}
+
+// sha512Crypt is Ulrich Drepper's SHA-512 crypt; salt is at most 16 characters.
+func sha512Crypt(password []byte, salt string, rounds int) string {
+	prefix := "$6$"
+	if rounds != sha512CryptDefaultRounds {
+		prefix += "rounds=" + strconv.Itoa(rounds) + "$"
+	}
+	return prefix + salt + "$" + cryptEncode(shaCrypt(sha512.New(), password, salt, rounds), sha512CryptOrder)
+}
+
+// shaCrypt is the digest shared by SHA-256 and SHA-512 crypt.
+func shaCrypt(h hash.Hash, password []byte, salt string, rounds int) []byte {
+	// repeat returns n bytes of b over and over
+	repeat := func(b []byte, n int) []byte {
+		var out []byte
+		for len(out) < n {
+			out = append(out, b[:min(len(b), n-len(out))]...)
+		}
+		return out
+	}
+
+	h.Write([]byte(string(password) + salt + string(password)))
+	alt := h.Sum(nil)
+	h.Reset()
+	h.Write(password)
+	h.Write([]byte(salt))
+	h.Write(repeat(alt, len(password)))
+	for n := len(password); n > 0; n >>= 1 {
+		if n&1 == 1 {
+			h.Write(alt)
+		} else {
+			h.Write(password)
+		}
+	}
+	a := h.Sum(nil)
+
+	h.Reset()
+	for range password {
+		h.Write(password)
+	}
+	p := repeat(h.Sum(nil), len(password))
+	h.Reset()
+	for i := 0; i < 16+int(a[0]); i++ {
+		h.Write([]byte(salt))
+	}
+	s := h.Sum(nil)[:len(salt)]
+
+	c := a
+	for i := 0; i < rounds; i++ {
+		h.Reset()
+		if i%2 == 1 {
+			h.Write(p)
+		} else {
+			h.Write(c)
+		}
+		if i%3 != 0 {
+			h.Write(s)
+		}
+		if i%7 != 0 {
+			h.Write(p)
+		}
+		if i%2 == 1 {
+			h.Write(c)
+		} else {
+			h.Write(p)
+		}
+		c = h.Sum(c[:0])
+	}
+	return c
+}
+
+var md5CryptOrder = []int{0, 6, 12, 1, 7, 13, 2, 8, 14, 3, 9, 15, 4, 10, 5, 11}
+
+// apr1Crypt is the FreeBSD MD5 crypt under Apache's $apr1$ magic; salt is
+// at most 8 characters.
+func apr1Crypt(password []byte, salt string) string {
+	const magic = "$apr1$"
+	alt := md5.Sum([]byte(string(password) + salt + string(password)))
+	h := md5.New()
+	h.Write([]byte(string(password) + magic + salt))
+	for n := len(password); n > 0; n -= 16 {
+		h.Write(alt[:min(n, 16)])
+	}
+	for n := len(password); n > 0; n >>= 1 {
+		if n&1 == 1 {
+			h.Write([]byte{0})
+		} else {
+			h.Write(password[:1])
+		}
+	}
+	final := h.Sum(nil)
+
+	for i := 0; i < 1000; i++ {
+		h.Reset()
+		if i%2 == 1 {
+			h.Write(password)
+		} else {
+			h.Write(final)
+		}
+		if i%3 != 0 {
+			h.Write([]byte(salt))
+		}
+		if i%7 != 0 {
+			h.Write(password)
+		}
+		if i%2 == 1 {
+			h.Write(final)
+		} else {
+			h.Write(password)
+		}
+		final = h.Sum(final[:0])
+	}
+	return magic + salt + "$" + cryptEncode(final, md5CryptOrder)
+}
+
+// ssha is OpenLDAP's {SSHA}: SHA-1 over the password and salt, then the salt.
+func ssha(password, salt []byte) string {
+	sum := sha1.Sum(append(append([]byte{}, password...), salt...))
+	return "{SSHA}" + base64.StdEncoding.EncodeToString(append(sum[:], salt...))
+}
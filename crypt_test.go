@@ -0,0 +1,58 @@
+package main
+
+import "testing"
+
+func TestSHA512Crypt(t *testing.T) {
+	// The examples in Drepper's SHA-crypt specification
+	for _, tt := range []struct {
+		salt   string
+		rounds int
+		want   string
+	}{
+		{"saltstring", 5000, "$6$saltstring$svn8UoSVapNtMuq1ukKS4tPQd8iKwSMHWjl/O817G3uBnIFNjnQJuesI68u4OTLiBFdcbYEdFCoEOfaS35inz1"},
+		{"saltstringsaltst", 10000, "$6$rounds=10000$saltstringsaltst$OW1/O6BYHV6BcXZu8QVeXbDWra3Oeqh0sbHbbMCVNSnCM/UrjmM0Dp8vOuZeHBy/YTBmSK6H9qs/y3RnOaw5v."},
+	} {
+		if got := sha512Crypt([]byte("Hello world!"), tt.salt, tt.rounds); got != tt.want {
+			t.Errorf("sha512Crypt(rounds=%d) = %s, want %s", tt.rounds, got, tt.want)
+		}
+	}
+}
+
+func TestAPR1(t *testing.T) {
+	// openssl passwd -apr1 -salt SALT PASSWORD
+	for _, tt := range []struct{ password, salt, want string }{
+		{"correct horse battery staple", "Zq8.x/Ab", "$apr1$Zq8.x/Ab$c0C/Mqnmae3tAngwAYl700"},
+		{"p", "s", "$apr1$s$BAtOygpU2TrPhOrm2Z1Qr1"},
+	} {
+		if got := apr1Crypt([]byte(tt.password), tt.salt); got != tt.want {
+			t.Errorf("apr1Crypt(%q) = %s, want %s", tt.password, got, tt.want)
+		}
+	}
+}
+
+func TestYescrypt(t *testing.T) {
+	// Checked against libxcrypt's crypt(3); the j9T and jBT settings
+	// prehash the password, j75 doesn't
+	salt := []byte("0123456789abcdef")
+	for _, tt := range []struct {
+		password string
+		ln, r    int
+		want     string
+	}{
+		{"correct horse battery staple", 12, 32, "$y$j9T$k2XAnEHBqQ1Ct2aMXFKNa/$9L6G/XyFKWAp.LHckuPX4e2T8SWaUtBrjoYMXp3QO.8"},
+		{"pencil", 10, 8, "$y$j75$k2XAnEHBqQ1Ct2aMXFKNa/$fZLmLKd/RQ6H4Ug0J0eKzmX/Do3uWZi.LLFzr3jAR24"},
+		{"pencil", 14, 32, "$y$jBT$k2XAnEHBqQ1Ct2aMXFKNa/$qKk1h/I1zLiAtO8nsLx/rmlHE2kRvuxzJdBRxlogqR7"},
+	} {
+		if got := yescryptHash([]byte(tt.password), salt, tt.ln, tt.r); got != tt.want {
+			t.Errorf("yescryptHash(%q, ln=%d, r=%d) = %s, want %s", tt.password, tt.ln, tt.r, got, tt.want)
+		}
+	}
+}
+
+func TestSSHA(t *testing.T) {
+	// base64(SHA-1(password || salt) || salt), as slappasswd writes it
+	salt := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	if got, want := ssha([]byte("pencil"), salt), "{SSHA}wQlfDRC2U2VxQ+eMKr7SFL0BmVgBAgMEBQYHCA=="; got != want {
+		t.Errorf("ssha(pencil) = %s, want %s", got, want)
+	}
+}
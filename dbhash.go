@@ -0,0 +1,67 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// ── Database verifiers ───────────────────────────────────────────────────────
+//
+// What PostgreSQL and MySQL store in place of a password, so a role can be
+// created from its verifier and the plaintext never reaches the server or
+// its statement log.
+
+const mysqlSaltLen = 20
+
+// pgScram is PostgreSQL's SCRAM-SHA-256 verifier (RFC 5802, RFC 7677):
+// the stored and server keys from the salted password. PostgreSQL runs
+// SASLprep over the password first, which leaves printable ASCII as it is
+// but may map or drop other characters, so hashPassword only passes ASCII.
+func pgScram(password, salt []byte, iter int) string {
+	salted := pbkdf2Key(sha256.New, password, salt, iter, 32)
+	key := func(name string) []byte {
+		mac := hmac.New(sha256.New, salted)
+		mac.Write([]byte(name))
+		return mac.Sum(nil)
+	}
+	stored := sha256.Sum256(key("Client Key"))
+	b64 := base64.StdEncoding.EncodeToString
+	return fmt.Sprintf("SCRAM-SHA-256$%d:%s$%s:%s", iter, b64(salt), b64(stored[:]), b64(key("Server Key")))
+}
+
+// mysqlNative is mysql_native_password: SHA-1 twice, in upper-case hex.
+func mysqlNative(password []byte) string {
+	once := sha1.Sum(password)
+	twice := sha1.Sum(once[:])
+	return "*" + strings.ToUpper(hex.EncodeToString(twice[:]))
+}
+
+// mysqlCachingSHA2 is caching_sha2_password's stored form: SHA-256 crypt
+// with a 20-character salt, rounds in thousands as three hex digits.
+func mysqlCachingSHA2(password []byte, salt string, rounds int) string {
+	digest := cryptEncode(shaCrypt(sha256.New(), password, salt, rounds), sha256CryptOrder)
+	return fmt.Sprintf("$A$%03X$%s%s", rounds/1000, salt, digest)
+}
+
+// pgCreateRole is SQL creating a login role with a SCRAM verifier.
+func pgCreateRole(role, verifier string) string {
+	return fmt.Sprintf(`CREATE ROLE "%s" LOGIN PASSWORD '%s';`, strings.ReplaceAll(role, `"`, `""`), verifier)
+}
+
+// mysqlCreateUser is SQL creating account, user or user@host, with a
+// stored hash for plugin. A bare user may connect from any host. Quotes are
+// doubled, which MySQL reads the same with or without NO_BACKSLASH_ESCAPES;
+// main rejects backslashes, which it doesn't.
+func mysqlCreateUser(account, plugin, hash string) string {
+	user, host, ok := strings.Cut(account, "@")
+	if !ok {
+		host = "%"
+	}
+	quote := strings.NewReplacer(`'`, `''`).Replace
+	return fmt.Sprintf("CREATE USER '%s'@'%s' IDENTIFIED WITH %s AS '%s';", quote(user), quote(host), plugin, hash)
+}
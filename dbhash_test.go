@@ -0,0 +1,108 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestPgScramRFC7677(t *testing.T) {
+	// The SCRAM-SHA-256 exchange in RFC 7677, section 3: user "user",
+	// password "pencil"
+	salt, _ := base64.StdEncoding.DecodeString("W22ZaJ0SNY7soEsUEjb6gQ==")
+	verifier := pgScram([]byte("pencil"), salt, 4096)
+	iter, keys, _ := strings.Cut(strings.TrimPrefix(verifier, "SCRAM-SHA-256$"), "$")
+	if iter != "4096:W22ZaJ0SNY7soEsUEjb6gQ==" {
+		t.Fatalf("verifier %s: wrong iterations or salt", verifier)
+	}
+	storedB64, serverB64, _ := strings.Cut(keys, ":")
+	storedKey, _ := base64.StdEncoding.DecodeString(storedB64)
+	serverKey, _ := base64.StdEncoding.DecodeString(serverB64)
+
+	authMessage := "n=user,r=rOprNGfwEbeRWgbNEkqO," +
+		"r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,s=W22ZaJ0SNY7soEsUEjb6gQ==,i=4096," +
+		"c=biws,r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0"
+	sign := func(key []byte) []byte {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(authMessage))
+		return mac.Sum(nil)
+	}
+
+	// The server signature the RFC's server sends
+	if got := base64.StdEncoding.EncodeToString(sign(serverKey)); got != "6rriTRBi23WpRR/wtup+mMhUZUn/dB5nLTJRsjl95G4=" {
+		t.Errorf("server signature %s, want the RFC's", got)
+	}
+	// The RFC's client proof, checked the way the server checks it
+	proof, _ := base64.StdEncoding.DecodeString("dHzbZapWIk4jUhN+Ute9ytag9zjfMHgsqmmiz7AndVQ=")
+	clientKey := sign(storedKey)
+	for i := range clientKey {
+		clientKey[i] ^= proof[i]
+	}
+	if sum := sha256.Sum256(clientKey); !hmac.Equal(sum[:], storedKey) {
+		t.Errorf("the RFC's client proof doesn't verify against stored key %s", storedB64)
+	}
+}
+
+func TestMysqlNative(t *testing.T) {
+	if got := mysqlNative([]byte("password")); got != "*2470C0C06DEE42FD1618BB99005ADCA2EC9D1E19" {
+		t.Errorf("mysqlNative(password) = %s", got)
+	}
+}
+
+func TestMysqlCachingSHA2(t *testing.T) {
+	// caching_sha2_password stores SHA-256 crypt with the rounds in thousands;
+	// these digests are glibc's crypt at its default 5000 rounds.
+	for _, tt := range []struct{ password, salt, want string }{
+		// The example in the SHA-crypt specification
+		{"Hello world!", "saltstring", "$A$005$saltstring5B8vYYiY.CVt1RlTTf8KbXBH3hsxY/GNooZaBBGWEc5"},
+		{"pencil", "Ab3.x/Qz9LmN2pR7", "$A$005$Ab3.x/Qz9LmN2pR7kaPfDpc0SWVivCVc2f649Viyi9AVUsifvL4fTfhvKj9"},
+	} {
+		if got := mysqlCachingSHA2([]byte(tt.password), tt.salt, 5000); got != tt.want {
+			t.Errorf("mysqlCachingSHA2(%q, %q) = %s, want %s", tt.password, tt.salt, got, tt.want)
+		}
+	}
+
+	// MySQL salts are 20 characters, past the 16 glibc keeps
+	cfg, err := parseHashConfig("mysql-caching-sha2", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := hashPassword("pencil", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(h, "$A$005$") || len(h) != len("$A$005$")+mysqlSaltLen+43 {
+		t.Errorf("hash %s isn't $A$005$, a %d-character salt and a 43-character digest", h, mysqlSaltLen)
+	}
+}
+
+func TestPgScramASCIIOnly(t *testing.T) {
+	cfg, err := parseHashConfig("pg-scram", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := hashPassword("pencil~!", cfg); err != nil {
+		t.Errorf("ASCII password: %v", err)
+	}
+	// U+00A0 and U+FB01 are ones SASLprep would change
+	for _, pw := range []string{"pen\u00a0cil", "\ufb01sh"} {
+		if _, err := hashPassword(pw, cfg); err == nil {
+			t.Errorf("hashPassword(%q) made a verifier PostgreSQL wouldn't match", pw)
+		}
+	}
+}
+
+func TestMysqlCreateUser(t *testing.T) {
+	// Doubled quotes read the same with and without NO_BACKSLASH_ESCAPES
+	got := mysqlCreateUser("o'brien@10.0.%", "mysql_native_password", "*ABC")
+	want := "CREATE USER 'o''brien'@'10.0.%' IDENTIFIED WITH mysql_native_password AS '*ABC';"
+	if got != want {
+		t.Errorf("mysqlCreateUser = %s, want %s", got, want)
+	}
+	_, errOut, err := runPassgen(t, "", "-no-copy", "-hash", "mysql-native", "-user", `app\x`)
+	if err == nil || !strings.Contains(errOut, "NO_BACKSLASH_ESCAPES") {
+		t.Errorf("-user with a backslash: %v\n%s", err, errOut)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+)
+
+// ── Deterministic site passwords ─────────────────────────────────────────────
+//
+// passgen derive stretches the master password with a memory-hard KDF salted
+// by site and login, expands the result with HKDF for the counter, and uses
+// it to key an AES-256-CTR keystream that replaces crypto/rand. Generation
+// then runs as usual, so the same inputs and flags always give the same
+// password. The parameters below are part of the output: changing any of
+// them changes every derived password.
+
+const deriveDomain = "passgen derive v1"
+
+var deriveKDFs = map[string]func(master, salt []byte) []byte{
+	"argon2id": func(master, salt []byte) []byte {
+		return argon2idKey(master, salt, 3, 64*1024, 4, 32) // 3 passes, 64 MiB, 4 lanes
+	},
+	"scrypt": func(master, salt []byte) []byte {
+		return scryptKey(master, salt, 1<<15, 8, 1, 32) // 32 MiB
+	},
+}
+
+// deriveKey returns the 32-byte key for one site, login and counter.
+func deriveKey(master, site, login string, counter int, kdf string) ([]byte, error) {
+	stretch, ok := deriveKDFs[kdf]
+	if !ok {
+		return nil, fmt.Errorf("-kdf must be argon2id or scrypt, not %q", kdf)
+	}
+	site = strings.ToLower(strings.TrimSpace(site))
+	salt := sha256.Sum256([]byte(deriveDomain + "\x00" + site + "\x00" + login))
+	stretched := stretch([]byte(master), salt[:])
+	info := deriveDomain + " " + kdf + " counter " + strconv.Itoa(counter)
+	return hkdfSHA256(stretched, salt[:], []byte(info), 32), nil
+}
+
+// keystream is an endless deterministic byte stream: AES-256-CTR over zeros.
+type keystream struct{ s cipher.Stream }
+
+func newKeystream(key []byte) (io.Reader, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return keystream{cipher.NewCTR(block, make([]byte, aes.BlockSize))}, nil
+}
+
+func (k keystream) Read(p []byte) (int, error) {
+	clear(p)
+	k.s.XORKeyStream(p, p)
+	return len(p), nil
+}
+
+// stdinIsTerminal reports whether someone is typing at stdin.
+func stdinIsTerminal() bool {
+	fi, err := os.Stdin.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// stderrIsTerminal reports whether stderr goes to a terminal rather than a
+// file, pipe or log.
+func stderrIsTerminal() bool {
+	fi, err := os.Stderr.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// readSecret prompts on the terminal without echoing what's typed. When
+// stdin isn't a terminal it reads one line, so scripts can pipe one in.
+func readSecret(prompt string) (string, error) {
+	if stdinIsTerminal() {
+		fmt.Fprint(os.Stderr, prompt)
+		if err := setEcho(false); err != nil {
+			return "", fmt.Errorf("can't turn off terminal echo: %v", err)
+		}
+		// Put echo back even if the user gives up with Ctrl-C
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt)
+		go func() {
+			if _, ok := <-sig; ok {
+				setEcho(true)
+				fmt.Fprintln(os.Stderr)
+				os.Exit(130)
+			}
+		}()
+		defer func() {
+			signal.Stop(sig)
+			close(sig)
+			setEcho(true)
+			fmt.Fprintln(os.Stderr)
+		}()
+	}
+	line, err := reader.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	secret := strings.TrimRight(line, "\r\n")
+	if secret == "" {
+		return "", fmt.Errorf("empty master password")
+	}
+	return secret, nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/hex"
+	"io"
+	"strings"
+	"testing"
+)
+
+// Derived passwords must never change: each of these pins the KDF
+// parameters, the HKDF salt and info, the keystream and how generation
+// consumes it. A failure here means every user's site passwords changed.
+
+const deriveMaster = "correct horse battery staple"
+
+func TestDeriveKey(t *testing.T) {
+	for _, tt := range []struct{ kdf, key, stream string }{
+		{"argon2id", "242ceeb95fc0f20e327b98eafc54a52a3852c8d6f87a03f499de5b398af0a6f7", "ee563b9663a39554e2201c72de03cb2d"},
+		{"scrypt", "0c3b3dfac47df004f42e535538c23462ff5b5acaf06426fc2578fb43e8b27b83", "3c8e08fdeec68f61a3339131c8628caa"},
+	} {
+		key, err := deriveKey(deriveMaster, "github.com", "ops@acme", 1, tt.kdf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := hex.EncodeToString(key); got != tt.key {
+			t.Errorf("%s key = %s, want %s", tt.kdf, got, tt.key)
+		}
+		ks, err := newKeystream(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stream := make([]byte, 16)
+		io.ReadFull(ks, stream)
+		if got := hex.EncodeToString(stream); got != tt.stream {
+			t.Errorf("%s keystream starts %s, want %s", tt.kdf, got, tt.stream)
+		}
+	}
+}
+
+func TestDerivePasswords(t *testing.T) {
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-site", "github.com", "-login", "ops@acme"}, "n;r3#c$xZ&|P5I;="},
+		{[]string{"-site", "github.com", "-login", "ops@acme", "-counter", "2"}, "3#@@rnYAnov8N_7t"},
+		{[]string{"-site", "bank.example", "-rules", "maxlength: 20; required: upper; required: digit"}, "O07X63WID7ADTHT1"},
+		// Sites are compared case-insensitively
+		{[]string{"-site", "GitHub.com", "-login", "ops@acme", "-type", "segment"}, "ShKg-h5df-6gCJ"},
+		{[]string{"-site", "vpn", "-type", "segment", "-kdf", "scrypt"}, "l9Xm-SGpA-FD9Q"},
+		{[]string{"-site", "github.com", "-login", "ops@acme", "-type", "phrase"}, "Lion-Risk-Link-Rays-427"},
+	} {
+		args := append([]string{"derive", "-no-copy"}, tt.args...)
+		out, errOut, err := runPassgen(t, deriveMaster+"\n", args...)
+		if err != nil {
+			t.Fatalf("passgen %s: %v\n%s", strings.Join(args, " "), err, errOut)
+		}
+		if got := strings.TrimSpace(out); got != tt.want {
+			t.Errorf("passgen %s = %q, want %q", strings.Join(args, " "), got, tt.want)
+		}
+	}
+}
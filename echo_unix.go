@@ -0,0 +1,19 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"os/exec"
+)
+
+// setEcho turns terminal echo on or off with stty.
+func setEcho(on bool) error {
+	arg := "-echo"
+	if on {
+		arg = "echo"
+	}
+	cmd := exec.Command("stty", arg)
+	cmd.Stdin = os.Stdin
+	return cmd.Run()
+}
@@ -0,0 +1,30 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+const enableEchoInput = 0x4
+
+var procSetConsoleMode = syscall.NewLazyDLL("kernel32.dll").NewProc("SetConsoleMode")
+
+// setEcho turns console echo on or off.
+func setEcho(on bool) error {
+	h := syscall.Handle(os.Stdin.Fd())
+	var mode uint32
+	if err := syscall.GetConsoleMode(h, &mode); err != nil {
+		return err
+	}
+	if on {
+		mode |= enableEchoInput
+	} else {
+		mode &^= enableEchoInput
+	}
+	if ok, _, err := procSetConsoleMode.Call(uintptr(h), uintptr(mode)); ok == 0 {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,607 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"unicode"
+)
+
+// ── Entropy estimates ────────────────────────────────────────────────────────
+//
+// Figures are log2 of the number of passwords a configuration can produce:
+// the space an attacker who knows every setting still has to search.
+
+const entropyPrec = 256
+
+func log2Float(x *big.Float) float64 {
+	if x.Sign() <= 0 {
+		return math.Inf(-1)
+	}
+	mant := new(big.Float)
+	exp := x.MantExp(mant)
+	m, _ := mant.Float64()
+	return math.Log2(m) + float64(exp)
+}
+
+func newFloat(v float64) *big.Float {
+	return new(big.Float).SetPrec(entropyPrec).SetFloat64(v)
+}
+
+func powFloat(base float64, n int) *big.Float {
+	result := newFloat(1)
+	b := newFloat(base)
+	for ; n > 0; n >>= 1 {
+		if n&1 == 1 {
+			result.Mul(result, b)
+		}
+		b.Mul(b, b)
+	}
+	return result
+}
+
+// guaranteed returns how many characters each set is promised: its explicit
+// minimum, then one for every other set while there's room left in length.
+func guaranteed(mins []int, length int) []int {
+	need := make([]int, len(mins))
+	room := length
+	for k, m := range mins {
+		need[k] = m
+		room -= m
+	}
+	for k, m := range mins {
+		if m == 0 && room > 0 {
+			need[k] = 1
+			room--
+		}
+	}
+	return need
+}
+
+// coverageSpace counts length-n strings over disjoint sets of the given
+// sizes in which set k appears at least need[k] times.
+func coverageSpace(sizes, need []int, n int) *big.Float {
+	// ways[t] = strings of length t using only the sets folded in so far
+	ways := make([]*big.Float, n+1)
+	for t := range ways {
+		ways[t] = newFloat(0)
+	}
+	ways[0] = newFloat(1)
+	binom := newFloat(0)
+	for k, size := range sizes {
+		next := make([]*big.Float, n+1)
+		for t := range next {
+			next[t] = newFloat(0)
+		}
+		for t := 0; t <= n; t++ {
+			if ways[t].Sign() == 0 {
+				continue
+			}
+			for j := need[k]; t+j <= n; j++ {
+				// choose which j of the t+j positions belong to set k
+				binom.SetInt(new(big.Int).Binomial(int64(t+j), int64(j)))
+				term := new(big.Float).SetPrec(entropyPrec).Mul(ways[t], binom)
+				term.Mul(term, powFloat(float64(size), j))
+				next[t+j].Add(next[t+j], term)
+			}
+		}
+		ways = next
+	}
+	return ways[n]
+}
+
+// requiredSpace counts passwords made of the given runs that contain at
+// least one character from each of the (possibly overlapping) required
+// sets, by inclusion–exclusion over which sets are missing.
+func requiredSpace(runs []charRun, required []string) *big.Float {
+	total := newFloat(0)
+	for mask := 0; mask < 1<<len(required); mask++ {
+		missing := ""
+		for k, set := range required {
+			if mask&(1<<k) != 0 {
+				missing += set
+			}
+		}
+		term := newFloat(1)
+		for _, r := range runs {
+			pool := len(uniqueRunes(filterChars(r.charset, missing)))
+			term.Mul(term, powFloat(float64(pool), r.n))
+		}
+		if bitCount(mask)%2 == 1 {
+			total.Sub(total, term)
+		} else {
+			total.Add(total, term)
+		}
+	}
+	return total
+}
+
+func bitCount(x int) int {
+	n := 0
+	for ; x > 0; x &= x - 1 {
+		n++
+	}
+	return n
+}
+
+// log2Adjacent returns log2 of how many length-n strings over charset pass
+// the run, sequence and keyboard-walk constraints (-Inf if none do). It
+// walks a transfer matrix over the last two characters and the current run
+// length, renormalising each step to stay within float64 range. Characters
+// that can't be part of a sequence or walk, such as most of a -unicode set,
+// behave alike and share one state, so the matrix stays small.
+func log2Adjacent(charset []rune, n int, c Constraints) float64 {
+	window := c.NoSequences || c.NoKeyboardWalks
+	if n == 0 || len(charset) == 0 || (c.MaxConsecutive == 0 && !window) {
+		return float64(n) * math.Log2(float64(len(charset)))
+	}
+
+	// States: each character that can take part in a sequence or walk,
+	// then one for all the others, weighted by how many there are
+	var active []rune
+	inert := 0
+	for _, ch := range charset {
+		lower := unicode.ToLower(ch)
+		_, onKeyboard := keyPositions[ch]
+		if window && (onKeyboard || lower >= 'a' && lower <= 'z' || lower >= '0' && lower <= '9') {
+			active = append(active, ch)
+		} else {
+			inert++
+		}
+	}
+	weight := make([]float64, len(active), len(active)+1)
+	for i := range weight {
+		weight[i] = 1
+	}
+	if inert > 0 {
+		weight = append(weight, float64(inert))
+	}
+	k := len(weight)
+
+	runs := 1
+	if c.MaxConsecutive > 0 {
+		runs = min(c.MaxConsecutive, n)
+	}
+	prevs := 1 // index k stands for "no character yet"
+	var bad []bool
+	if window {
+		prevs = k + 1
+		na := len(active)
+		bad = make([]bool, na*na*na)
+		for a, ra := range active {
+			for b, rb := range active {
+				for d, rd := range active {
+					bad[(a*na+b)*na+d] = (c.NoSequences && isSequence(ra, rb, rd)) ||
+						(c.NoKeyboardWalks && isKeyboardWalk(ra, rb, rd))
+				}
+			}
+		}
+	}
+	isBad := func(a, b, d int) bool {
+		na := len(active)
+		return a < na && b < na && d < na && bad[(a*na+b)*na+d]
+	}
+	idx := func(a, b, r int) int { return (a*k+b)*runs + r }
+
+	f := make([]float64, prevs*k*runs)
+	for b := 0; b < k; b++ {
+		a := 0
+		if window {
+			a = k
+		}
+		f[idx(a, b, 0)] = weight[b]
+	}
+	scale := 0.0
+	for pos := 1; pos < n; pos++ {
+		g := make([]float64, len(f))
+		for a := 0; a < prevs; a++ {
+			for b := 0; b < k; b++ {
+				for r := 0; r < runs; r++ {
+					v := f[idx(a, b, r)]
+					if v == 0 {
+						continue
+					}
+					for d := 0; d < k; d++ {
+						na := 0
+						if window {
+							if isBad(a, b, d) {
+								continue
+							}
+							na = b
+						}
+						if d != b || c.MaxConsecutive == 0 {
+							g[idx(na, d, 0)] += v * weight[d]
+							continue
+						}
+						// The same state again: the same character extends
+						// the run, any other one in the state starts anew
+						if r+1 < runs {
+							g[idx(na, d, r+1)] += v
+						}
+						g[idx(na, d, 0)] += v * (weight[d] - 1)
+					}
+				}
+			}
+		}
+		sum := 0.0
+		for _, v := range g {
+			sum += v
+		}
+		if sum == 0 {
+			return math.Inf(-1)
+		}
+		for i := range g {
+			g[i] /= sum
+		}
+		scale += math.Log2(sum)
+		f = g
+	}
+	sum := 0.0
+	for _, v := range f {
+		sum += v
+	}
+	return scale + math.Log2(sum)
+}
+
+// charRun is a stretch of a password drawn from one charset, such as a
+// segment; adjacency constraints restart at each run.
+type charRun struct {
+	charset string
+	n       int
+}
+
+// maxRejectionBits caps what the adjacency constraints or -max-length may
+// cost: beyond it rejection sampling would throw away too many draws to be
+// practical.
+const maxRejectionBits = 12
+
+// constraintCost returns how many bits of entropy the constraints remove
+// from a password made of the given runs. Adjacency rules restart at each
+// run; uniqueness spans them all. The two effects are treated as
+// independent, which makes the combined figure an estimate.
+func constraintCost(runs []charRun, c Constraints) (float64, error) {
+	if !c.active() {
+		return 0, nil
+	}
+	cost := 0.0
+	adjacent := make(map[charRun]float64)
+	for _, r := range runs {
+		chars := uniqueRunes(r.charset)
+		adj, seen := adjacent[r]
+		if !seen {
+			adj = log2Adjacent(chars, r.n, c)
+			adjacent[r] = adj
+		}
+		if math.IsInf(adj, -1) {
+			return 0, fmt.Errorf("constraints are unsatisfiable: no %d-character run over %d characters passes them", r.n, len(chars))
+		}
+		cost += float64(r.n)*math.Log2(float64(len(chars))) - adj
+	}
+	// Rejection sampling needs about 2^cost draws per password
+	if cost > maxRejectionBits {
+		return 0, fmt.Errorf("constraints are too strict: only about 1 in 2^%.0f passwords passes them — loosen them or allow more characters", cost)
+	}
+
+	if c.UniqueChars {
+		total, union := 0, ""
+		for _, r := range runs {
+			total += r.n
+			union += r.charset
+		}
+		if n := len(uniqueRunes(union)); total > n {
+			return 0, fmt.Errorf("constraints are unsatisfiable: %d unique characters needed but only %d available", total, n)
+		}
+		// Each position loses the characters earlier positions are
+		// expected to have already taken from its charset.
+		for i, r := range runs {
+			chars := uniqueRunes(r.charset)
+			if r.n > len(chars) {
+				return 0, fmt.Errorf("constraints are unsatisfiable: %d unique characters needed from a set of %d", r.n, len(chars))
+			}
+			taken := 0.0
+			for _, e := range runs[:i] {
+				shared := len(chars) - len(uniqueRunes(filterChars(string(chars), e.charset)))
+				taken += float64(e.n) * float64(shared) / float64(len(uniqueRunes(e.charset)))
+			}
+			for j := 0; j < r.n; j++ {
+				avail := math.Max(float64(len(chars))-taken-float64(j), 1)
+				cost += math.Log2(float64(len(chars))) - math.Log2(avail)
+			}
+		}
+	}
+	return cost, nil
+}
+
+// randomEntropy returns the entropy of a random-mode password and how much
+// of it the constraints cost, or an error if the config can't be satisfied.
+func randomEntropy(cfg RandomConfig) (bits, cost float64, err error) {
+	sets, mins, charset, err := randomSets(cfg)
+	if err != nil {
+		return 0, 0, err
+	}
+	if charset == "" {
+		return 0, 0, fmt.Errorf("no characters available — all sets excluded")
+	}
+	need := guaranteed(mins, cfg.Length)
+	if cfg.UniqueChars {
+		for k, set := range sets {
+			if n := len(uniqueRunes(set)); need[k] > n {
+				return 0, 0, fmt.Errorf("constraints are unsatisfiable: %d unique characters needed from a set of %d", need[k], n)
+			}
+		}
+	}
+
+	var space *big.Float
+	if cfg.Charset != "" {
+		var required []string
+		for k, set := range sets {
+			if need[k] > 0 {
+				required = append(required, set)
+			}
+		}
+		if len(required) > 16 {
+			required = nil // too many to enumerate; ignore the guarantees
+		}
+		space = requiredSpace([]charRun{{charset, cfg.Length}}, required)
+	} else {
+		sizes := make([]int, len(sets))
+		for k, set := range sets {
+			sizes[k] = len(uniqueRunes(set))
+		}
+		space = coverageSpace(sizes, need, cfg.Length)
+	}
+
+	cost, err = constraintCost([]charRun{{charset, cfg.Length}}, cfg.Constraints)
+	if err != nil {
+		return 0, 0, err
+	}
+	return log2Float(space) - cost, cost, nil
+}
+
+// segmentEntropy returns the entropy of a segmented password and what the
+// class coverage guarantee and the constraints each cost, or an error if
+// the config can't be satisfied.
+func segmentEntropy(cfg SegmentConfig) (bits float64, costs []entropyCost, err error) {
+	segs, err := segmentLayout(cfg)
+	if err != nil {
+		return 0, nil, err
+	}
+	runs := make([]charRun, len(segs))
+	for i, seg := range segs {
+		runs[i] = charRun{seg.charset, seg.n}
+		bits += float64(seg.n) * math.Log2(float64(len(uniqueRunes(seg.charset))))
+	}
+
+	if cfg.Coverage != "" {
+		covered := 0.0
+		switch cfg.Coverage {
+		case "per-segment":
+			for i, seg := range segs {
+				covered += log2Float(requiredSpace(runs[i:i+1], seg.classes))
+			}
+		case "overall":
+			covered = log2Float(requiredSpace(runs, coverageClasses(segs)))
+		}
+		costs = append(costs, entropyCost{"class coverage", bits - covered})
+		bits = covered
+	}
+
+	cost, err := constraintCost(runs, cfg.Constraints)
+	if err != nil {
+		return 0, nil, err
+	}
+	costs = append(costs, entropyCost{"constraints", cost})
+	return bits - cost, costs, nil
+}
+
+// entropyCost is how many bits one option takes off an entropy figure.
+type entropyCost struct {
+	what string
+	bits float64
+}
+
+// entropyNote formats an entropy figure for display, listing any
+// non-negligible costs.
+func entropyNote(bits float64, costs ...entropyCost) string {
+	var parts []string
+	for _, c := range costs {
+		if c.bits > 0.05 {
+			parts = append(parts, fmt.Sprintf("%s %.1f", c.what, c.bits))
+		}
+	}
+	if len(parts) == 0 {
+		return fmt.Sprintf("Entropy: ~%.1f bits", bits)
+	}
+	return fmt.Sprintf("Entropy: ~%.1f bits (cost in bits: %s)", bits, strings.Join(parts, ", "))
+}
+
+// passphraseEntropy returns the entropy of a passphrase and what -max-length
+// costs, or an error if no passphrase can satisfy the bounds. Included words
+// are the user's own choice, so only the random transformations applied to
+// them count; for random words the transformations are averaged over the list.
+func passphraseEntropy(cfg PassphraseConfig) (float64, []entropyCost, error) {
+	list := uniqueStrings(phraseWords(cfg))
+	if len(list) == 0 {
+		return 0, nil, fmt.Errorf("no words of the requested length in the word list")
+	}
+	included := 0
+	fixedLen := 0
+	wordBits := 0.0
+	for _, w := range cfg.Include {
+		if w = nfc(strings.TrimSpace(w)); w != "" {
+			included++
+			fixedLen += len(clusters(w))
+			wordBits += transformEntropy(cfg, w)
+		}
+	}
+
+	random := cfg.Words - included
+	if cfg.ShuffleInclude && included > 0 {
+		// which slots the included words land in, and in what order
+		counts := make(map[string]int)
+		for _, w := range cfg.Include {
+			if w = strings.TrimSpace(w); w != "" {
+				counts[w]++
+			}
+		}
+		for i := 0; i < included; i++ {
+			wordBits += math.Log2(float64(cfg.Words - i))
+		}
+		for _, k := range counts {
+			for i := 2; i <= k; i++ {
+				wordBits -= math.Log2(float64(i))
+			}
+		}
+	}
+	lists := make([][]string, random)
+	for i := range lists {
+		lists[i] = list
+	}
+	return phraseEntropy(cfg, wordBits, fixedLen, lists)
+}
+
+// sentenceEntropy returns the entropy of a sentence passphrase: each slot
+// contributes its own list's size.
+func sentenceEntropy(cfg SentenceConfig) (float64, []entropyCost, error) {
+	return phraseEntropy(cfg.PassphraseConfig, 0, 0, templateLists(cfg.Template))
+}
+
+// phraseEntropy adds to bits the random words drawn one from each of lists,
+// the number and the separators, then the cost of -max-length. fixedLen is
+// the length of words that are not drawn at random.
+func phraseEntropy(cfg PassphraseConfig, bits float64, fixedLen int, lists [][]string) (float64, []entropyCost, error) {
+	for _, list := range lists {
+		list = uniqueStrings(list)
+		avg := 0.0
+		for _, w := range list {
+			avg += transformEntropy(cfg, w)
+		}
+		avg /= float64(len(list))
+		bits += math.Log2(float64(len(list))) + avg
+	}
+
+	bits += phraseExtras(cfg, cfg.Words)
+
+	if cfg.MaxLength == 0 {
+		return bits, nil, nil
+	}
+	// Resampling to fit keeps only the passphrases short enough: the cost
+	// is -log2 of the chance a draw fits, found by convolving the length
+	// distributions of the random words and the number.
+	sepLen := len(clusters(cfg.Separator))
+	if cfg.SeparatorSet != "" {
+		sepLen = 1
+	}
+	tokens := cfg.Words
+	if cfg.AddNumber {
+		tokens++
+	}
+	fixedLen += (tokens - 1) * sepLen
+	if fixedLen > cfg.MaxLength {
+		return 0, nil, fmt.Errorf("included words and separators alone take %d characters, over -max-length %d", fixedLen, cfg.MaxLength)
+	}
+	room := cfg.MaxLength - fixedLen
+	dist := make([]float64, room+1) // dist[n] = chance the random parts take n characters
+	dist[0] = 1
+	convolve := func(lengths map[int]float64) {
+		next := make([]float64, room+1)
+		for n, p := range dist {
+			for l, q := range lengths {
+				if n+l <= room {
+					next[n+l] += p * q
+				}
+			}
+		}
+		dist = next
+	}
+	for _, list := range lists {
+		wordLens := make(map[int]float64)
+		for _, w := range list {
+			wordLens[len(clusters(w))] += 1 / float64(len(list))
+		}
+		convolve(wordLens)
+	}
+	if cfg.AddNumber {
+		convolve(map[int]float64{1: 0.01, 2: 0.09, 3: 0.9})
+	}
+	fits := 0.0
+	for _, p := range dist {
+		fits += p
+	}
+	if fits == 0 {
+		return 0, nil, fmt.Errorf("no passphrase of %d words fits in -max-length %d", cfg.Words, cfg.MaxLength)
+	}
+	cost := -math.Log2(fits)
+	if cost > maxRejectionBits {
+		return 0, nil, fmt.Errorf("only about 1 in 2^%.0f passphrases of %d words fits in -max-length %d — raise it or use fewer words", cost, cfg.Words, cfg.MaxLength)
+	}
+	return bits - cost, []entropyCost{{"max-length", cost}}, nil
+}
+
+// phraseExtras returns the bits the number and random separators add to a
+// passphrase of n words.
+func phraseExtras(cfg PassphraseConfig, n int) float64 {
+	bits := 0.0
+	tokens := n
+	if cfg.AddNumber {
+		bits += math.Log2(1000)
+		if cfg.NumberAnywhere {
+			bits += math.Log2(float64(n + 1))
+		}
+		tokens++
+	}
+	if seps := len(uniqueRunes(cfg.SeparatorSet)); seps > 0 && tokens > 1 {
+		bits += float64(tokens-1) * math.Log2(float64(seps))
+	}
+	return bits
+}
+
+// transformEntropy returns the bits the per-word transformations add to w.
+func transformEntropy(cfg PassphraseConfig, w string) float64 {
+	bits := 0.0
+	if cfg.ShuffleChars {
+		cs := clusters(w)
+		if cfg.Capitalize || cfg.RandomCase {
+			cs = clusters(strings.ToLower(w))
+		}
+		// distinct orderings: n! / (product of repeat counts!)
+		counts := make(map[string]int)
+		for i, c := range cs {
+			counts[c]++
+			bits += math.Log2(float64(i + 1))
+		}
+		for _, k := range counts {
+			for i := 2; i <= k; i++ {
+				bits -= math.Log2(float64(i))
+			}
+		}
+	}
+	if cfg.RandomCase {
+		// three equally likely forms, some of which may coincide
+		forms := make(map[string]float64)
+		for _, f := range []string{capitalizeWord(w), strings.ToUpper(w), strings.ToLower(w)} {
+			forms[f] += 1.0 / 3
+		}
+		for _, p := range forms {
+			bits -= p * math.Log2(p)
+		}
+	}
+	if cfg.Leet && leet(w) != w {
+		bits++
+	}
+	return bits
+}
+
+// uniqueStrings returns the distinct strings of list in order.
+func uniqueStrings(list []string) []string {
+	seen := make(map[string]bool, len(list))
+	var out []string
+	for _, s := range list {
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// ── Framework secrets ────────────────────────────────────────────────────────
+//
+// Each preset makes the secret its framework's own generator would: the
+// same length, alphabet and encoding, so it drops straight into settings.
+
+// djangoChars is the alphabet of Django's get_random_secret_key.
+const djangoChars = "abcdefghijklmnopqrstuvwxyz0123456789!@#$%^&*(-_=+)"
+
+type frameworkPreset struct {
+	bits     float64
+	generate func() (string, error)
+}
+
+// encodedBytes returns a preset of n random bytes in encode.
+func encodedBytes(n int, encode func([]byte) string) frameworkPreset {
+	return frameworkPreset{float64(8 * n), func() (string, error) {
+		b, err := randBytes(n)
+		if err != nil {
+			return "", err
+		}
+		return encode(b), nil
+	}}
+}
+
+var frameworkPresets = map[string]frameworkPreset{
+	// django-admin startproject: 50 characters
+	"django": {50 * math.Log2(float64(len(djangoChars))), func() (string, error) {
+		key := make([]byte, 50)
+		for i := range key {
+			n, err := randInt(len(djangoChars))
+			if err != nil {
+				return "", err
+			}
+			key[i] = djangoChars[n]
+		}
+		return string(key), nil
+	}},
+	// bin/rails secret: SecureRandom.hex(64)
+	"rails-secret-key-base": encodedBytes(64, hex.EncodeToString),
+	// php artisan key:generate for AES-256-CBC
+	"laravel-app-key": encodedBytes(32, func(b []byte) string {
+		return "base64:" + base64.StdEncoding.EncodeToString(b)
+	}),
+	// Fernet.generate_key(): 32 bytes, URL-safe base64 with padding
+	"fernet": encodedBytes(32, base64.URLEncoding.EncodeToString),
+	// RFC 7518 wants an HS256 key of at least 256 bits; base64url as in a JWK
+	"jwt-hs256": encodedBytes(32, base64.RawURLEncoding.EncodeToString),
+	// Flask's documented secrets.token_hex()
+	"flask": encodedBytes(32, hex.EncodeToString),
+}
+
+func frameworkPresetNames() string {
+	var names []string
+	for name := range frameworkPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// lookupPreset returns the named preset.
+func lookupPreset(name string) (frameworkPreset, error) {
+	if name == "" {
+		return frameworkPreset{}, fmt.Errorf("framework mode needs -preset: %s", frameworkPresetNames())
+	}
+	p, ok := frameworkPresets[strings.ToLower(name)]
+	if !ok {
+		return p, fmt.Errorf("-preset must be one of %s, not %q", frameworkPresetNames(), name)
+	}
+	return p, nil
+}
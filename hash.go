@@ -0,0 +1,154 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ── Password hashes ──────────────────────────────────────────────────────────
+//
+// -hash prints each password's hash for the server side to store, in the
+// format verifying libraries read: PHC strings for Argon2id, scrypt and
+// PBKDF2, modular crypt ($2b$, $6$, $y$, $apr1$) for bcrypt and the system
+// schemes, {SSHA} for LDAP, and what PostgreSQL and MySQL store. Salts
+// always come from crypto/rand, even under passgen derive.
+
+// hashParam is one tunable cost parameter and the values it may take.
+type hashParam struct {
+	name          string
+	def, min, max int
+}
+
+// hashSchemes lists each -hash scheme's parameters, in output order.
+var hashSchemes = map[string][]hashParam{
+	"bcrypt":             {{"cost", 12, 4, 31}},
+	"argon2id":           {{"m", 64 * 1024, 8, 4 * 1024 * 1024}, {"t", 3, 1, 1000}, {"p", 4, 1, 255}}, // m in KiB
+	"scrypt":             {{"ln", 17, 1, 30}, {"r", 8, 1, 64}, {"p", 1, 1, 64}},                       // N = 2^ln
+	"pbkdf2-sha256":      {{"i", 600000, 1, 1 << 30}, {"l", 32, 16, 64}},                              // l = hash bytes
+	"sha512-crypt":       {{"rounds", sha512CryptDefaultRounds, 1000, 999999999}},
+	"yescrypt":           {{"ln", 12, 10, 20}, {"r", 32, 1, 32}}, // libxcrypt's default, j9T
+	"apr1":               nil,
+	"ssha":               nil,
+	"pg-scram":           {{"i", 4096, 4096, 1 << 30}},
+	"mysql-native":       nil,
+	"mysql-caching-sha2": {{"rounds", 5000, 5000, 0xfff * 1000}}, // stored in thousands
+}
+
+const hashSchemeNames = "bcrypt, argon2id, scrypt, pbkdf2-sha256, sha512-crypt, yescrypt, apr1, ssha, pg-scram, mysql-native or mysql-caching-sha2"
+
+type HashConfig struct {
+	Scheme string
+	Params map[string]int
+}
+
+// parseHashConfig checks scheme and fills in its parameters from raw, a
+// comma-separated list like "m=65536,t=3", defaulting the ones left out.
+func parseHashConfig(scheme, raw string) (HashConfig, error) {
+	scheme = strings.ToLower(scheme)
+	params, ok := hashSchemes[scheme]
+	if !ok {
+		return HashConfig{}, fmt.Errorf("-hash must be %s, not %q", hashSchemeNames, scheme)
+	}
+	cfg := HashConfig{Scheme: scheme, Params: make(map[string]int)}
+	for _, p := range params {
+		cfg.Params[p.name] = p.def
+	}
+	for _, field := range strings.FieldsFunc(raw, func(r rune) bool { return r == ',' || r == ' ' }) {
+		name, value, _ := strings.Cut(field, "=")
+		var param *hashParam
+		for i := range params {
+			if params[i].name == name {
+				param = &params[i]
+			}
+		}
+		if param == nil && len(params) == 0 {
+			return cfg, fmt.Errorf("-hash-params: %s takes no parameters", scheme)
+		}
+		if param == nil {
+			var names []string
+			for _, p := range params {
+				names = append(names, p.name)
+			}
+			return cfg, fmt.Errorf("-hash-params: %s takes %s, not %q", scheme, strings.Join(names, ", "), name)
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < param.min || n > param.max {
+			return cfg, fmt.Errorf("-hash-params: %s must be %d–%d for %s, not %q", name, param.min, param.max, scheme, value)
+		}
+		cfg.Params[name] = n
+	}
+	if scheme == "argon2id" && cfg.Params["m"] < 8*cfg.Params["p"] {
+		return cfg, fmt.Errorf("-hash-params: argon2id needs m >= 8×p KiB")
+	}
+	if scheme == "mysql-caching-sha2" && cfg.Params["rounds"]%1000 != 0 {
+		return cfg, fmt.Errorf("-hash-params: mysql-caching-sha2 rounds must be a multiple of 1000")
+	}
+	if (scheme == "scrypt" || scheme == "yescrypt") && 128*cfg.Params["r"]<<cfg.Params["ln"] > 1<<31 {
+		return cfg, fmt.Errorf("-hash-params: %s with ln=%d, r=%d needs over 2 GiB — lower ln or r", scheme, cfg.Params["ln"], cfg.Params["r"])
+	}
+	return cfg, nil
+}
+
+// hashPassword hashes password under a fresh random salt.
+func hashPassword(password string, cfg HashConfig) (string, error) {
+	salt := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return "", err
+	}
+	pw, p := []byte(password), cfg.Params
+	b64 := base64.RawStdEncoding.EncodeToString
+
+	switch cfg.Scheme {
+	case "bcrypt":
+		return bcryptHash(pw, salt, p["cost"])
+	case "argon2id":
+		key := argon2idKey(pw, salt, uint32(p["t"]), uint32(p["m"]), uint32(p["p"]), 32)
+		return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2Version, p["m"], p["t"], p["p"], b64(salt), b64(key)), nil
+	case "scrypt":
+		key := scryptKey(pw, salt, 1<<p["ln"], p["r"], p["p"], 32)
+		return fmt.Sprintf("$scrypt$ln=%d,r=%d,p=%d$%s$%s", p["ln"], p["r"], p["p"], b64(salt), b64(key)), nil
+	case "pbkdf2-sha256":
+		key := pbkdf2Key(sha256.New, pw, salt, p["i"], p["l"])
+		return fmt.Sprintf("$pbkdf2-sha256$i=%d,l=%d$%s$%s", p["i"], p["l"], b64(salt), b64(key)), nil
+	case "sha512-crypt":
+		return sha512Crypt(pw, cryptEncoding.EncodeToString(salt[:12]), p["rounds"]), nil
+	case "yescrypt":
+		return yescryptHash(pw, salt, p["ln"], p["r"]), nil
+	case "apr1":
+		return apr1Crypt(pw, cryptEncoding.EncodeToString(salt[:6])), nil
+	case "ssha":
+		return ssha(pw, salt[:8]), nil
+	case "pg-scram":
+		for _, ch := range password {
+			if ch > unicode.MaxASCII {
+				return "", fmt.Errorf("pg-scram: PostgreSQL runs SASLprep over non-ASCII passwords, which passgen doesn't — use ASCII characters only, not %q", ch)
+			}
+		}
+		return pgScram(pw, salt, p["i"]), nil
+	case "mysql-native":
+		return mysqlNative(pw), nil
+	case "mysql-caching-sha2":
+		return mysqlCachingSHA2(pw, cryptEncoding.EncodeToString(salt)[:mysqlSaltLen], p["rounds"]), nil
+	}
+	return "", fmt.Errorf("unknown hash scheme %q", cfg.Scheme)
+}
+
+// accountLine is what -user prints in place of the bare hash: SQL creating
+// the role for the database schemes, a user:hash line for the others.
+func accountLine(scheme, user, hash string) string {
+	switch scheme {
+	case "pg-scram":
+		return pgCreateRole(user, hash)
+	case "mysql-native":
+		return mysqlCreateUser(user, "mysql_native_password", hash)
+	case "mysql-caching-sha2":
+		return mysqlCreateUser(user, "caching_sha2_password", hash)
+	}
+	return user + ":" + hash
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+func TestBcrypt(t *testing.T) {
+	for _, tt := range []struct {
+		password, salt string
+		cost           int
+		want           string
+	}{
+		// Openwall's crypt_blowfish vectors, including its 8-bit one
+		{"U*U", "CCCCCCCCCCCCCCCCCCCCC.", 5, "$2b$05$CCCCCCCCCCCCCCCCCCCCC.E5YPO9kmyuRGyh0XouQYb4YMJKvyOeW"},
+		{"\xa3", "/OK.fbVrR/bpIqNJ5ianF.", 5, "$2b$05$/OK.fbVrR/bpIqNJ5ianF.Sa7shbm4.OzKpvFnX1pQLmQW96oUlCq"},
+		// Checked against libxcrypt
+		{"correct horse battery staple", "KBCwKxOzLha2MUDgW0PjXe", 5, "$2b$05$KBCwKxOzLha2MUDgW0PjXeqpiWLd8vAgzynRwMuRLijYkvm7iSsD2"},
+		{"", "KBCwKxOzLha2MUDgW0PjXe", 4, "$2b$04$KBCwKxOzLha2MUDgW0PjXeg/oaNzVlQFb3jg.67P.r1snBL8ZffHa"},
+	} {
+		salt, err := bcryptEncoding.DecodeString(tt.salt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := bcryptHash([]byte(tt.password), salt, tt.cost)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("bcryptHash(%q) = %s, want %s", tt.password, got, tt.want)
+		}
+	}
+}
+
+func TestArgon2id(t *testing.T) {
+	// The argon2id vectors of golang.org/x/crypto/argon2
+	for _, tt := range []struct {
+		time, memory, lanes uint32
+		want                string
+	}{
+		{1, 64, 1, "655ad15eac652dc59f7170a7332bf49b8469be1fdb9c28bb"},
+		{2, 64, 2, "350ac37222f436ccb5c0972f1ebd3bf6b958bf2071841362"},
+		{3, 256, 2, "4668d30ac4187e6878eedeacf0fd83c5a0a30db2cc16ef0b"},
+		{4, 1024, 8, "8dafa8e004f8ea96bf7c0f93eecf67a6047476143d15577f"},
+		{2, 64, 3, "4a15b31aec7c2590b87d1f520be7d96f56658172deaa3079"},
+	} {
+		key := argon2idKey([]byte("password"), []byte("somesalt"), tt.time, tt.memory, tt.lanes, 24)
+		if got := hex.EncodeToString(key); got != tt.want {
+			t.Errorf("argon2id t=%d m=%d p=%d = %s, want %s", tt.time, tt.memory, tt.lanes, got, tt.want)
+		}
+	}
+}
+
+func TestScrypt(t *testing.T) {
+	// RFC 7914, section 12
+	for _, tt := range []struct {
+		password, salt string
+		n, r, p        int
+		want           string
+	}{
+		{"", "", 16, 1, 1, "77d6576238657b203b19ca42c18a0497f16b4844e3074ae8dfdffa3fede21442fcd0069ded0948f8326a753a0fc81f17e8d3e0fb2e0d3628cf35e20c38d18906"},
+		{"password", "NaCl", 1024, 8, 16, "fdbabe1c9d3472007856e7190d01e9fe7c6ad7cbc8237830e77376634b3731622eaf30d92e22a3886ff109279d9830dac727afb94a83ee6d8360cbdfa2cc0640"},
+	} {
+		key := scryptKey([]byte(tt.password), []byte(tt.salt), tt.n, tt.r, tt.p, 64)
+		if got := hex.EncodeToString(key); got != tt.want {
+			t.Errorf("scrypt(%q, N=%d) = %s, want %s", tt.password, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestPBKDF2SHA256(t *testing.T) {
+	// RFC 7914, section 11
+	for _, tt := range []struct {
+		password, salt string
+		iter           int
+		want           string
+	}{
+		{"passwd", "salt", 1, "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783"},
+		{"Password", "NaCl", 80000, "4ddcd8f60b98be21830cee5ef22701f9641a4418d04c0414aeff08876b34ab56a1d425a1225833549adb841b51c9b3176a272bdebba1d078478f62b397f33c8d"},
+	} {
+		key := pbkdf2Key(sha256.New, []byte(tt.password), []byte(tt.salt), tt.iter, 64)
+		if got := hex.EncodeToString(key); got != tt.want {
+			t.Errorf("pbkdf2-sha256(%q, i=%d) = %s, want %s", tt.password, tt.iter, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestChaCha20RFC8439(t *testing.T) {
+	// RFC 8439, section 2.4.2: the sunscreen example, which starts at block
+	// counter 1, so the first block of keystream is skipped
+	key, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f")
+	nonce, _ := hex.DecodeString("000000000000004a00000000")
+	plaintext := "Ladies and Gentlemen of the class of '99: If I could offer you only one tip for the future, sunscreen would be it."
+	want := "6e2e359a2568f98041ba0728dd0d6981e97e7aec1d4360c20a27afccfd9fae0b" +
+		"f91b65c5524733ab8f593dabcd62b3571639d624e65152ab8f530c359f0861d8" +
+		"07ca0dbf500d6a6156a38e088a22b65e52bc514d16ccf806818ce91ab7793736" +
+		"5af90bbf74a35be6b40b8eedf2785e42874d"
+
+	c := newChaCha20(key, nonce)
+	skip := make([]byte, 64)
+	c.XORKeyStream(skip, skip)
+	got := []byte(plaintext)
+	// Odd-sized pieces cross block boundaries mid-call
+	for _, part := range [][]byte{got[:7], got[7:70], got[70:]} {
+		c.XORKeyStream(part, part)
+	}
+	if hex.EncodeToString(got) != want {
+		t.Errorf("ciphertext %x, want %s", got, want)
+	}
+}
+
+// openKDBX decrypts a KDBX 4 file the way KeePass does, checking the header
+// hash and every HMAC, and returns the XML document and the inner stream key.
+func openKDBX(data []byte, master string) (xmlText, innerKey []byte, err error) {
+	if len(data) < 12 || !bytes.Equal(data[:12], []byte{0x03, 0xd9, 0xa2, 0x9a, 0x67, 0xfb, 0x4b, 0xb5, 0x00, 0x00, 0x04, 0x00}) {
+		return nil, nil, errors.New("not a KDBX 4.0 file")
+	}
+	fields := make(map[byte][]byte)
+	pos := 12
+	for {
+		id, n := data[pos], int(binary.LittleEndian.Uint32(data[pos+1:]))
+		fields[id] = data[pos+5 : pos+5+n]
+		pos += 5 + n
+		if id == 0 {
+			break
+		}
+	}
+	header := data[:pos]
+	if sum := sha256.Sum256(header); !bytes.Equal(sum[:], data[pos:pos+32]) {
+		return nil, nil, errors.New("header hash mismatch")
+	}
+	headerMAC := data[pos+32 : pos+64]
+	pos += 64
+
+	// The KDF's variant dictionary
+	kdf := make(map[string][]byte)
+	for d := fields[11][2:]; d[0] != 0; {
+		kn := int(binary.LittleEndian.Uint32(d[1:]))
+		key := string(d[5 : 5+kn])
+		vn := int(binary.LittleEndian.Uint32(d[5+kn:]))
+		kdf[key] = d[9+kn : 9+kn+vn]
+		d = d[9+kn+vn:]
+	}
+	if hex.EncodeToString(kdf["$UUID"]) != kdbxArgon2id {
+		return nil, nil, errors.New("KDF isn't Argon2id")
+	}
+	passwordHash := sha256.Sum256([]byte(master))
+	composite := sha256.Sum256(passwordHash[:])
+	transformed := argon2idKey(composite[:], kdf["S"],
+		uint32(binary.LittleEndian.Uint64(kdf["I"])),
+		uint32(binary.LittleEndian.Uint64(kdf["M"])/1024),
+		binary.LittleEndian.Uint32(kdf["P"]), 32)
+	seeded := append(append([]byte{}, fields[4]...), transformed...)
+	encKey := sha256.Sum256(seeded)
+	hmacBase := sha512.Sum512(append(seeded, 1))
+	blockMAC := func(index uint64, data []byte) []byte {
+		key := sha512.Sum512(append(binary.LittleEndian.AppendUint64(nil, index), hmacBase[:]...))
+		mac := hmac.New(sha256.New, key[:])
+		mac.Write(data)
+		return mac.Sum(nil)
+	}
+	if !hmac.Equal(headerMAC, blockMAC(^uint64(0), header)) {
+		return nil, nil, errors.New("header HMAC mismatch: wrong master password")
+	}
+
+	var body []byte
+	for index := uint64(0); ; index++ {
+		mac, n := data[pos:pos+32], int(binary.LittleEndian.Uint32(data[pos+32:]))
+		chunk := data[pos+36 : pos+36+n]
+		signed := append(binary.LittleEndian.AppendUint64(nil, index), data[pos+32:pos+36+n]...)
+		if !hmac.Equal(mac, blockMAC(index, signed)) {
+			return nil, nil, errors.New("block HMAC mismatch")
+		}
+		pos += 36 + n
+		if n == 0 {
+			break
+		}
+		body = append(body, chunk...)
+	}
+	if pos != len(data) {
+		return nil, nil, errors.New("data after the last block")
+	}
+
+	switch hex.EncodeToString(fields[2]) {
+	case kdbxCiphers["aes256"].uuid:
+		block, _ := aes.NewCipher(encKey[:])
+		cipher.NewCBCDecrypter(block, fields[7]).CryptBlocks(body, body)
+		pad := int(body[len(body)-1])
+		if pad < 1 || pad > aes.BlockSize || !bytes.Equal(body[len(body)-pad:], bytes.Repeat([]byte{byte(pad)}, pad)) {
+			return nil, nil, errors.New("bad CBC padding")
+		}
+		body = body[:len(body)-pad]
+	case kdbxCiphers["chacha20"].uuid:
+		newChaCha20(encKey[:], fields[7]).XORKeyStream(body, body)
+	default:
+		return nil, nil, errors.New("unknown cipher")
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, err
+	}
+	payload, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Inner header, then the XML
+	for {
+		id, n := payload[0], int(binary.LittleEndian.Uint32(payload[1:]))
+		switch id {
+		case 1:
+			if binary.LittleEndian.Uint32(payload[5:]) != 3 {
+				return nil, nil, errors.New("inner stream isn't ChaCha20")
+			}
+		case 2:
+			innerKey = payload[5 : 5+n]
+		}
+		payload = payload[5+n:]
+		if id == 0 {
+			return payload, innerKey, nil
+		}
+	}
+}
+
+func TestKDBXRoundTrip(t *testing.T) {
+	accounts := []Account{
+		{Title: "Mail", Username: "ann@example.com", URL: "https://mail.example.com", Notes: "<work> & home"},
+		{Title: "Bank", Username: "ann"},
+	}
+	passwords := []string{"k7#Vq!x2Lm", "ünïcødé-pässwörd"}
+	for _, cipherName := range []string{"aes256", "chacha20"} {
+		data, err := kdbxDatabase("master pw", cipherName, "Vault", accounts, passwords)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := openKDBX(data, "wrong pw"); err == nil {
+			t.Errorf("%s: opened with the wrong master password", cipherName)
+		}
+		xmlText, innerKey, err := openKDBX(data, "master pw")
+		if err != nil {
+			t.Fatalf("%s: %v", cipherName, err)
+		}
+		var doc kdbxDocument
+		if err := xml.Unmarshal(xmlText, &doc); err != nil {
+			t.Fatalf("%s: %v", cipherName, err)
+		}
+		if doc.Meta.DatabaseName != "Vault" || doc.Root.Group.Name != "Vault" || len(doc.Root.Group.Entry) != len(accounts) {
+			t.Fatalf("%s: database %q, group %q with %d entries", cipherName, doc.Meta.DatabaseName, doc.Root.Group.Name, len(doc.Root.Group.Entry))
+		}
+
+		streamKey := sha512.Sum512(innerKey)
+		inner := newChaCha20(streamKey[:32], streamKey[32:44])
+		for i, e := range doc.Root.Group.Entry {
+			got := make(map[string]kdbxValue)
+			for _, s := range e.String {
+				got[s.Key] = s.Value
+			}
+			a := accounts[i]
+			if got["Title"].Text != a.Title || got["UserName"].Text != a.Username || got["URL"].Text != a.URL || got["Notes"].Text != a.Notes {
+				t.Errorf("%s: entry %d is %v, want %+v", cipherName, i, got, a)
+			}
+			pw, err := base64.StdEncoding.DecodeString(got["Password"].Text)
+			if err != nil || got["Password"].Protected != "True" {
+				t.Fatalf("%s: entry %d password isn't protected base64: %v", cipherName, i, err)
+			}
+			inner.XORKeyStream(pw, pw)
+			if string(pw) != passwords[i] {
+				t.Errorf("%s: entry %d password %q, want %q", cipherName, i, pw, passwords[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"hash"
+	"math/bits"
+)
+
+// ── Key derivation: PBKDF2, HKDF, scrypt ─────────────────────────────────────
+//
+// In-tree so passgen stays dependency-free on Go 1.21.
+
+// pbkdf2Key is PBKDF2 (RFC 8018) with HMAC over h.
+func pbkdf2Key(h func() hash.Hash, password, salt []byte, iter, keyLen int) []byte {
+	prf := hmac.New(h, password)
+	size := prf.Size()
+	var key []byte
+	for block := uint32(1); len(key) < keyLen; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		prf.Write(binary.BigEndian.AppendUint32(nil, block))
+		u := prf.Sum(nil)
+		t := append([]byte{}, u...)
+		for i := 1; i < iter; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		key = append(key, t[:min(size, keyLen-len(key))]...)
+	}
+	return key
+}
+
+// hkdfSHA256 is HKDF (RFC 5869) with SHA-256: extract a key from secret
+// and salt, then expand it to n bytes bound to info.
+func hkdfSHA256(secret, salt, info []byte, n int) []byte {
+	if salt == nil {
+		salt = make([]byte, sha256.Size)
+	}
+	extract := hmac.New(sha256.New, salt)
+	extract.Write(secret)
+	prk := extract.Sum(nil)
+
+	expand := hmac.New(sha256.New, prk)
+	var out, t []byte
+	for i := byte(1); len(out) < n; i++ {
+		expand.Reset()
+		expand.Write(t)
+		expand.Write(info)
+		expand.Write([]byte{i})
+		t = expand.Sum(nil)
+		out = append(out, t...)
+	}
+	return out[:n]
+}
+
+// scryptKey is scrypt (RFC 7914) with cost n (a power of two), block size
+// r and parallelism p.
+func scryptKey(password, salt []byte, n, r, p, keyLen int) []byte {
+	b := pbkdf2Key(sha256.New, password, salt, 1, p*128*r)
+	words := 32 * r
+	x := make([]uint32, words)
+	v := make([]uint32, words*n)
+	for i := 0; i < p; i++ {
+		chunk := b[i*128*r : (i+1)*128*r]
+		for j := range x {
+			x[j] = binary.LittleEndian.Uint32(chunk[j*4:])
+		}
+		// ROMix: fill V with successive mixes, then read it back in an
+		// order that depends on the data
+		for j := 0; j < n; j++ {
+			copy(v[j*words:], x)
+			scryptBlockMix(x, r)
+		}
+		for j := 0; j < n; j++ {
+			k := int(x[words-16] & uint32(n-1))
+			for w := range x {
+				x[w] ^= v[k*words+w]
+			}
+			scryptBlockMix(x, r)
+		}
+		for j, w := range x {
+			binary.LittleEndian.PutUint32(chunk[j*4:], w)
+		}
+	}
+	return pbkdf2Key(sha256.New, password, b, 1, keyLen)
+}
+
+// scryptBlockMix mixes 2r 64-byte blocks with Salsa20/8, writing the
+// even-numbered outputs first.
+func scryptBlockMix(b []uint32, r int) {
+	y := make([]uint32, len(b))
+	var x [16]uint32
+	copy(x[:], b[(2*r-1)*16:])
+	for i := 0; i < 2*r; i++ {
+		for j := range x {
+			x[j] ^= b[i*16+j]
+		}
+		salsa20(&x, 8)
+		dst := (i/2 + (i%2)*r) * 16
+		copy(y[dst:], x[:])
+	}
+	copy(b, y)
+}
+
+// salsa20 is the Salsa20 core with the given number of rounds.
+func salsa20(b *[16]uint32, rounds int) {
+	x := *b
+	q := func(a, b, c, d int) {
+		x[b] ^= bits.RotateLeft32(x[a]+x[d], 7)
+		x[c] ^= bits.RotateLeft32(x[b]+x[a], 9)
+		x[d] ^= bits.RotateLeft32(x[c]+x[b], 13)
+		x[a] ^= bits.RotateLeft32(x[d]+x[c], 18)
+	}
+	for i := 0; i < rounds; i += 2 {
+		// columns, then rows
+		q(0, 4, 8, 12)
+		q(5, 9, 13, 1)
+		q(10, 14, 2, 6)
+		q(15, 3, 7, 11)
+		q(0, 1, 2, 3)
+		q(5, 6, 7, 4)
+		q(10, 11, 8, 9)
+		q(15, 12, 13, 14)
+	}
+	for i := range b {
+		b[i] += x[i]
+	}
+}
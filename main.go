@@ -3,13 +3,20 @@ package main
 import (
 	"bufio"
 	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
 	"flag"
 	"fmt"
-	"math/big"
+	"io"
+	"math"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 const (
@@ -17,26 +24,138 @@ const (
 	charLowercase = "abcdefghijklmnopqrstuvwxyz"
 	charDigits    = "0123456789"
 	charSymbols   = "!@#$%^&*()-_=+[]{}|;:,.<>?"
+	charPunct     = "!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~" // all printable ASCII punctuation
 )
 
+// Named -symbols presets: which symbols survive pasting into each context unquoted.
+var symbolPresets = map[string]string{
+	"safe": "-_.",                                    // shell words, URLs, SQL literals, YAML, connection strings
+	"ldap": filterChars(charPunct, ",+\"\\<>;=#*()"), // DN and search-filter specials removed
+	"xml":  filterChars(charPunct, "<>&'\""),         // no entity or attribute-quote characters
+	"json": filterChars(charPunct, "\"\\"),           // nothing that needs escaping in a JSON string
+}
+
+// Lookalike groups removed by -no-ambiguous: characters in a group are
+// easily mistaken for one another on screen.
+var ambiguousGroups = []string{
+	"0Oo",  // zero, capital and small o
+	"1lI|", // one, small L, capital i, pipe
+	"5S",
+	"2Z",
+	"8B",
+	"`'", // backtick, apostrophe
+}
+
+// Extra groups removed by -no-homoglyphs, for passwords that get printed,
+// scanned or written down by hand.
+var homoglyphGroups = []string{
+	"cC", "kK", "pP", "sS", "uUvV", "wW", "xX", "zZ", // same shape in both cases
+	"6Gb", "9gq", "17", "!i", "4A",
+	";:", ",.", "-_~", "\"", "()[]{}",
+}
+
+// lookalikes returns the characters to exclude for the chosen level.
+func lookalikes(noAmbiguous, noHomoglyphs bool) string {
+	var sb strings.Builder
+	if noAmbiguous || noHomoglyphs {
+		for _, g := range ambiguousGroups {
+			sb.WriteString(g)
+		}
+		sb.WriteString(scriptConfusables)
+	}
+	if noHomoglyphs {
+		for _, g := range homoglyphGroups {
+			sb.WriteString(g)
+		}
+	}
+	return sb.String()
+}
+
+// resolveSymbols returns the symbol set named by a preset, or the literal
+// characters given.
+func resolveSymbols(v string) (string, error) {
+	if preset, ok := symbolPresets[strings.ToLower(v)]; ok {
+		return preset, nil
+	}
+	if err := checkCharset("-symbols", v); err != nil {
+		return "", err
+	}
+	for _, ch := range v {
+		if unicode.IsLetter(ch) || unicode.IsDigit(ch) {
+			return "", fmt.Errorf("-symbols must not contain letters or digits (%q) — use -charset for a fully custom set", ch)
+		}
+	}
+	return string(uniqueRunes(v)), nil
+}
+
+// checkCharset rejects empty sets and whitespace or control characters,
+// which are almost always a copy-paste accident, and combining marks left
+// over after nfc, which can't stand as characters of their own. Characters
+// made of several code points, like emoji sequences, are rejected too:
+// passwords are drawn a code point at a time, which would break them apart.
+func checkCharset(flagName, v string) error {
+	if v == "" {
+		return fmt.Errorf("%s must not be empty", flagName)
+	}
+	for _, ch := range v {
+		if unicode.IsSpace(ch) || unicode.IsControl(ch) {
+			return fmt.Errorf("%s contains whitespace or a control character (%q)", flagName, ch)
+		}
+		if unicode.In(ch, unicode.Mn, unicode.Me) {
+			return fmt.Errorf("%s contains a combining mark (%U) — use precomposed characters", flagName, ch)
+		}
+	}
+	for _, c := range clusters(v) {
+		if utf8.RuneCountInString(c) > 1 {
+			return fmt.Errorf("%s contains %q, one character made of several code points, which passgen can't draw", flagName, c)
+		}
+	}
+	return nil
+}
+
 // ── Crypto helpers ────────────────────────────────────────────────────────────
 
+// randSource feeds every random choice; passgen derive swaps in a
+// deterministic keystream.
+var randSource io.Reader = rand.Reader
+
+// randInt returns a uniform integer in [0, max), drawing 64 bits at a time
+// and redrawing the few values past the last whole multiple of max.
 func randInt(max int) (int, error) {
-	n, err := rand.Int(rand.Reader, big.NewInt(int64(max)))
-	if err != nil {
-		return 0, err
+	limit := math.MaxUint64 - math.MaxUint64%uint64(max)
+	var buf [8]byte
+	for {
+		if _, err := io.ReadFull(randSource, buf[:]); err != nil {
+			return 0, err
+		}
+		if v := binary.BigEndian.Uint64(buf[:]); v < limit {
+			return int(v % uint64(max)), nil
+		}
+	}
+}
+
+func randBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := io.ReadFull(randSource, b); err != nil {
+		return nil, err
 	}
-	return int(n.Int64()), nil
+	return b, nil
 }
 
+// filterChars removes the characters in exclude from s, comparing whole
+// user-perceived characters: excluding q̇ leaves q alone.
 func filterChars(s, exclude string) string {
 	if exclude == "" {
 		return s
 	}
+	drop := make(map[string]bool)
+	for _, c := range clusters(exclude) {
+		drop[c] = true
+	}
 	var sb strings.Builder
-	for _, ch := range s {
-		if !strings.ContainsRune(exclude, ch) {
-			sb.WriteRune(ch)
+	for _, c := range clusters(s) {
+		if !drop[c] {
+			sb.WriteString(c)
 		}
 	}
 	return sb.String()
@@ -169,30 +288,105 @@ type RandomConfig struct {
 	NoDigits  bool
 	NoSymbols bool
 	Exclude   string
+
+	Symbols string   // replaces charSymbols when set
+	Unicode []string // extra character sets, each a class of its own
+
+	// Per-class minimum counts; 0 still guarantees one character when there's room
+	MinUpper   int
+	MinLower   int
+	MinDigits  int
+	MinSymbols int
+
+	// Set by -charset or -rules: Charset replaces the class flags and each
+	// Required set is guaranteed at least one character.
+	Charset  string
+	Required []string
+
+	Constraints
 }
 
 type SegmentConfig struct {
 	Segments  int
 	SegLength int
-	Separator string // "-" or "_"
+	Lengths   []int  // per-segment lengths; overrides Segments and SegLength when set
+	Separator string // any non-empty string, e.g. "-", "_", " :: "
 	NoUpper   bool
 	NoLower   bool
 	NoDigits  bool
+	Symbols   string   // symbol set to include; segments have none by default
+	Unicode   []string // extra character sets added to the shared charset
 	Exclude   string
+	Charset   string   // replaces the class flags when set
+	Classes   []string // per-segment class pattern like "d" or "ul"; "*" = the shared charset
+	Coverage  string   // "overall" or "per-segment": guarantee a character from every class
+
+	Constraints
 }
 
 type PassphraseConfig struct {
-	Words        int
-	Separator    string
-	Capitalize   bool
-	AddNumber    bool
-	Include      []string // user's own words to mix in
-	ShuffleChars bool     // scramble letters within each word
+	Words          int
+	Separator      string
+	SeparatorSet   string // when set, each gap gets a random character from it instead of Separator
+	Capitalize     bool
+	RandomCase     bool // each word randomly Capitalized, UPPER or lower; overrides Capitalize
+	Leet           bool // each word randomly gets leet substitutions (a→4, e→3, …) or not
+	AddNumber      bool
+	NumberAnywhere bool     // put the number at a random word boundary instead of the end
+	Include        []string // user's own words to mix in
+	ShuffleInclude bool     // place included words at random positions instead of first
+	ShuffleChars   bool     // scramble letters within each word
+	MinWordLen     int      // shortest random word allowed, 0 = no limit
+	MaxWordLen     int      // longest random word allowed, 0 = no limit
+	MaxLength      int      // longest passphrase in characters, 0 = no limit; words are resampled to fit
+}
+
+// MarkovConfig strings together made-up words from a character model until
+// the password reaches MinEntropy bits.
+type MarkovConfig struct {
+	MinEntropy float64
+
+	PassphraseConfig // separator, case, leet and number options; the word options are unused
+}
+
+// SentenceConfig fills a part-of-speech template instead of drawing words
+// from one list. Words, Include and the word-length bounds are unused.
+type SentenceConfig struct {
+	Template []string // slot names, e.g. adjective noun verb adverb
+
+	PassphraseConfig
+}
+
+// leetSubs are the substitutions -leet applies to a word.
+var leetSubs = map[rune]rune{
+	'a': '4', 'A': '4',
+	'e': '3', 'E': '3',
+	'i': '1', 'I': '1',
+	'o': '0', 'O': '0',
+	's': '5', 'S': '5',
+	't': '7', 'T': '7',
+}
+
+func leet(w string) string {
+	return strings.Map(func(r rune) rune {
+		if sub, ok := leetSubs[r]; ok {
+			return sub
+		}
+		return r
+	}, w)
+}
+
+func capitalizeWord(w string) string {
+	if w == "" {
+		return w
+	}
+	first, size := utf8.DecodeRuneInString(w)
+	return string(unicode.ToUpper(first)) + strings.ToLower(w[size:])
 }
 
 // ── Generators ────────────────────────────────────────────────────────────────
 
-func buildSets(noUpper, noLower, noDigits bool, noSymbols *bool, exclude string) (sets []string, fullCharset string) {
+func buildSets(noUpper, noLower, noDigits bool, symbols, exclude string, extra ...string) (sets []string, fullCharset string) {
 	var cs strings.Builder
 	addSet := func(chars string) {
 		filtered := filterChars(chars, exclude)
@@ -204,85 +398,430 @@ func buildSets(noUpper, noLower, noDigits bool, noSymbols *bool, exclude string)
 	if !noUpper { addSet(charUppercase) }
 	if !noLower { addSet(charLowercase) }
 	if !noDigits { addSet(charDigits) }
-	if noSymbols != nil && !*noSymbols { addSet(charSymbols) }
+	if symbols != "" { addSet(symbols) }
+	for _, set := range extra { addSet(set) }
 	return sets, cs.String()
 }
 
-func shuffleBytes(b []byte) error {
-	for i := len(b) - 1; i > 0; i-- {
+func shuffle[T any](s []T) error {
+	for i := len(s) - 1; i > 0; i-- {
 		j, err := randInt(i + 1)
 		if err != nil {
 			return err
 		}
-		b[i], b[j] = b[j], b[i]
+		s[i], s[j] = s[j], s[i]
 	}
 	return nil
 }
 
+// maxAttempts bounds rejection sampling before a config is declared unsatisfiable.
+const maxAttempts = 1000
+
+// rejectionAttempts bounds the draws for the character constraints and
+// -max-length, which the entropy estimates only allow when at least 1 in
+// 2^maxRejectionBits draws passes: failing this many times is then all but
+// impossible.
+const rejectionAttempts = 1 << 20
+
+// randomSets resolves the active character sets for random mode along with
+// the explicit minimum count requested from each (parallel to sets).
+func randomSets(cfg RandomConfig) (sets []string, mins []int, charset string, err error) {
+	if cfg.Charset != "" {
+		for _, set := range cfg.Required {
+			if set = filterChars(set, cfg.Exclude); set != "" {
+				sets = append(sets, set)
+				mins = append(mins, 0)
+			}
+		}
+		return sets, mins, filterChars(cfg.Charset, cfg.Exclude), nil
+	}
+
+	symbols := charSymbols
+	if cfg.Symbols != "" {
+		symbols = cfg.Symbols
+	}
+	type class struct {
+		name  string
+		off   bool
+		chars string
+		min   int
+	}
+	classes := []class{
+		{"uppercase", cfg.NoUpper, charUppercase, cfg.MinUpper},
+		{"lowercase", cfg.NoLower, charLowercase, cfg.MinLower},
+		{"digits", cfg.NoDigits, charDigits, cfg.MinDigits},
+		{"symbols", cfg.NoSymbols, symbols, cfg.MinSymbols},
+	}
+	for _, set := range cfg.Unicode {
+		classes = append(classes, class{"unicode", false, set, 0})
+	}
+	var cs strings.Builder
+	for _, c := range classes {
+		filtered := ""
+		if !c.off {
+			filtered = filterChars(c.chars, cfg.Exclude)
+		}
+		if filtered == "" {
+			if c.min > 0 {
+				return nil, nil, "", fmt.Errorf("at least %d %s required, but %s are disabled or excluded", c.min, c.name, c.name)
+			}
+			continue
+		}
+		sets = append(sets, filtered)
+		mins = append(mins, c.min)
+		cs.WriteString(filtered)
+	}
+	return sets, mins, cs.String(), nil
+}
+
 func generateRandom(cfg RandomConfig) (string, error) {
-	noSym := cfg.NoSymbols
-	sets, charset := buildSets(cfg.NoUpper, cfg.NoLower, cfg.NoDigits, &noSym, cfg.Exclude)
+	sets, mins, charset, err := randomSets(cfg)
+	if err != nil {
+		return "", err
+	}
 	if charset == "" {
 		return "", fmt.Errorf("no characters available — all sets excluded")
 	}
+	required := 0
+	for _, m := range mins {
+		required += m
+	}
+	if required > cfg.Length {
+		return "", fmt.Errorf("per-class minimums add up to %d, more than the length of %d", required, cfg.Length)
+	}
+	need := guaranteed(mins, cfg.Length)
+
+	for attempt := 0; attempt < rejectionAttempts; attempt++ {
+		// Lay out which set each position draws from — each explicit
+		// minimum, then one from every other active set while there's
+		// room, the full charset for the rest — and shuffle the layout
+		layout := make([]string, 0, cfg.Length)
+		for k, set := range sets {
+			for n := 0; n < need[k]; n++ {
+				layout = append(layout, set)
+			}
+		}
+		for len(layout) < cfg.Length {
+			layout = append(layout, charset)
+		}
+		if err := shuffle(layout); err != nil {
+			return "", err
+		}
 
-	password := make([]byte, cfg.Length)
-
-	// Guarantee at least one char from each active set
-	pos := 0
-	for _, set := range sets {
-		if pos >= cfg.Length {
-			break
+		var used map[rune]bool
+		if cfg.UniqueChars {
+			used = make(map[rune]bool)
 		}
-		idx, err := randInt(len(set))
+		password, ok, err := fillSlots(layout, cfg.Constraints, used)
 		if err != nil {
 			return "", err
 		}
-		password[pos] = set[idx]
-		pos++
+		if ok {
+			return nfc(string(password)), nil
+		}
 	}
+	return "", fmt.Errorf("could not satisfy the character constraints after %d attempts", rejectionAttempts)
+}
 
-	// Fill remainder from full charset
-	for i := pos; i < cfg.Length; i++ {
-		idx, err := randInt(len(charset))
-		if err != nil {
-			return "", err
+// segment is one segment's length, charset and the class sets that make it up
+// (none when it comes from -charset).
+type segment struct {
+	n       int
+	charset string
+	classes []string
+}
+
+// segmentLayout resolves the length, charset and classes of each segment.
+func segmentLayout(cfg SegmentConfig) ([]segment, error) {
+	lengths := cfg.Lengths
+	if len(lengths) == 0 {
+		lengths = make([]int, cfg.Segments)
+		for i := range lengths {
+			lengths[i] = cfg.SegLength
 		}
-		password[i] = charset[idx]
+	}
+	if len(cfg.Classes) > 0 && len(cfg.Classes) != len(lengths) {
+		return nil, fmt.Errorf("%d class patterns given for %d segments", len(cfg.Classes), len(lengths))
 	}
 
-	if err := shuffleBytes(password); err != nil {
-		return "", err
+	symbols := cfg.Symbols
+	if symbols == "" {
+		symbols = charSymbols
+	}
+	segs := make([]segment, len(lengths))
+	for i, n := range lengths {
+		seg := segment{n: n}
+		switch {
+		case len(cfg.Classes) > 0 && cfg.Classes[i] != "*":
+			p := cfg.Classes[i]
+			sym := ""
+			if strings.Contains(p, "s") {
+				sym = symbols
+			}
+			seg.classes, seg.charset = buildSets(!strings.Contains(p, "u"), !strings.Contains(p, "l"), !strings.Contains(p, "d"), sym, cfg.Exclude)
+		case cfg.Charset != "":
+			seg.charset = filterChars(cfg.Charset, cfg.Exclude)
+		default:
+			seg.classes, seg.charset = buildSets(cfg.NoUpper, cfg.NoLower, cfg.NoDigits, cfg.Symbols, cfg.Exclude, cfg.Unicode...)
+		}
+		if seg.charset == "" {
+			return nil, fmt.Errorf("no characters available for segment %d — all sets excluded", i+1)
+		}
+		if cfg.Coverage == "per-segment" && len(seg.classes) > n {
+			return nil, fmt.Errorf("segment %d has %d characters, too few to cover %d classes", i+1, n, len(seg.classes))
+		}
+		segs[i] = seg
+	}
+	if cfg.Coverage == "overall" {
+		total := 0
+		for _, seg := range segs {
+			total += seg.n
+		}
+		if k := len(coverageClasses(segs)); k > total {
+			return nil, fmt.Errorf("%d characters are too few to cover %d classes", total, k)
+		}
+	}
+	return segs, nil
+}
+
+// coverageClasses returns the distinct classes across all segments.
+func coverageClasses(segs []segment) []string {
+	var classes []string
+	for _, seg := range segs {
+		for _, c := range seg.classes {
+			if !slices.Contains(classes, c) {
+				classes = append(classes, c)
+			}
+		}
+	}
+	return classes
+}
+
+// segmentSlots decides which set each position of each segment draws from:
+// the segment charset, except where a coverage guarantee puts one class.
+// Guaranteed positions are shuffled within the segment boundaries.
+func segmentSlots(cfg SegmentConfig, segs []segment) ([][]string, error) {
+	slots := make([][]string, len(segs))
+	for i, seg := range segs {
+		slots[i] = make([]string, seg.n)
+		for j := range slots[i] {
+			slots[i][j] = seg.charset
+		}
+	}
+
+	switch cfg.Coverage {
+	case "per-segment":
+		for i, seg := range segs {
+			copy(slots[i], seg.classes)
+			if err := shuffle(slots[i]); err != nil {
+				return nil, err
+			}
+		}
+	case "overall":
+		// Visit every position in random order and give each class the
+		// first free one whose segment can hold it
+		var positions [][2]int
+		for i, seg := range segs {
+			for j := 0; j < seg.n; j++ {
+				positions = append(positions, [2]int{i, j})
+			}
+		}
+		if err := shuffle(positions); err != nil {
+			return nil, err
+		}
+		taken := make([]bool, len(positions))
+		for _, class := range coverageClasses(segs) {
+			placed := false
+			for k, pos := range positions {
+				if !taken[k] && slices.Contains(segs[pos[0]].classes, class) {
+					slots[pos[0]][pos[1]] = class
+					taken[k], placed = true, true
+					break
+				}
+			}
+			if !placed {
+				return nil, fmt.Errorf("no room left to place class %q", class)
+			}
+		}
+	}
+	return slots, nil
+}
+
+// parseSegLengths parses a comma-separated list of segment lengths like "4,6,4".
+func parseSegLengths(raw string) ([]int, error) {
+	var lengths []int
+	for _, f := range strings.Split(raw, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("-seg-lengths: %q is not a length >= 1", strings.TrimSpace(f))
+		}
+		lengths = append(lengths, n)
+	}
+	return lengths, nil
+}
+
+// parseSegClasses parses per-segment class patterns like "d,ul,*": each
+// entry combines u (upper), l (lower), d (digits) and s (symbols), and "*"
+// keeps the shared charset.
+func parseSegClasses(raw string) ([]string, error) {
+	var classes []string
+	for _, f := range strings.Split(raw, ",") {
+		p := strings.ToLower(strings.TrimSpace(f))
+		if p == "" || p == "*" {
+			classes = append(classes, "*")
+			continue
+		}
+		for _, ch := range p {
+			if !strings.ContainsRune("ulds", ch) {
+				return nil, fmt.Errorf("-seg-classes: unknown class %q in %q — use u, l, d, s or *", ch, p)
+			}
+		}
+		classes = append(classes, p)
+	}
+	return classes, nil
+}
+
+// checkSeparator rejects empty separators and control characters.
+func checkSeparator(sep string) error {
+	if sep == "" {
+		return fmt.Errorf("-separator must not be empty")
+	}
+	for _, ch := range sep {
+		if unicode.IsControl(ch) {
+			return fmt.Errorf("-separator contains a control character (%q)", ch)
+		}
 	}
-	return string(password), nil
+	return nil
 }
 
 func generateSegmented(cfg SegmentConfig) (string, error) {
-	_, charset := buildSets(cfg.NoUpper, cfg.NoLower, cfg.NoDigits, nil, cfg.Exclude)
-	if charset == "" {
-		return "", fmt.Errorf("no characters available — all sets excluded")
+	segs, err := segmentLayout(cfg)
+	if err != nil {
+		return "", err
 	}
 
-	parts := make([]string, cfg.Segments)
-	for i := range parts {
-		seg := make([]byte, cfg.SegLength)
-		for j := range seg {
-			idx, err := randInt(len(charset))
+attempts:
+	for attempt := 0; attempt < rejectionAttempts; attempt++ {
+		slots, err := segmentSlots(cfg, segs)
+		if err != nil {
+			return "", err
+		}
+		var used map[rune]bool
+		if cfg.UniqueChars {
+			used = make(map[rune]bool)
+		}
+		parts := make([]string, len(segs))
+		for i := range parts {
+			seg, ok, err := fillSlots(slots[i], cfg.Constraints, used)
 			if err != nil {
 				return "", err
 			}
-			seg[j] = charset[idx]
+			if !ok {
+				continue attempts
+			}
+			parts[i] = string(seg)
+		}
+		return nfc(strings.Join(parts, cfg.Separator)), nil
+	}
+	return "", fmt.Errorf("could not satisfy the character constraints after %d attempts", rejectionAttempts)
+}
+
+// lengthWords is the pool -min-word-len and -max-word-len filter: wordList
+// holds four-letter words only, so the BIP39 words, of 3 to 8 letters, join
+// it.
+var lengthWords = func() []string {
+	words := append([]string(nil), wordList...)
+	seen := make(map[string]bool, len(wordList))
+	for _, w := range wordList {
+		seen[w] = true
+	}
+	for _, w := range bip39Words {
+		if !seen[w] {
+			words = append(words, w)
+		}
+	}
+	return words
+}()
+
+// phraseWords returns the word list, or with a word-length bound the words
+// of lengthWords within it.
+func phraseWords(cfg PassphraseConfig) []string {
+	if cfg.MinWordLen == 0 && cfg.MaxWordLen == 0 {
+		return wordList
+	}
+	var pool []string
+	for _, w := range lengthWords {
+		n := len(clusters(w))
+		if (cfg.MinWordLen == 0 || n >= cfg.MinWordLen) && (cfg.MaxWordLen == 0 || n <= cfg.MaxWordLen) {
+			pool = append(pool, w)
+		}
+	}
+	return pool
+}
+
+// defaultWords is the passphrase length when none is asked for: four words,
+// or two random words past the user's own so the phrase isn't all guessable.
+func defaultWords(included int) int {
+	return max(4, included+2)
+}
+
+// includeWarning explains when the user's own words make up most of a
+// passphrase: they are guessable and add nothing to its entropy.
+func includeWarning(included, total int) string {
+	if included == 0 || included*2 <= total {
+		return ""
+	}
+	return fmt.Sprintf("%d of %d words are your own — they aren't counted in the entropy figure; add random words with -words", included, total)
+}
+
+// checkPhraseSeparator rejects separators that would blur word boundaries:
+// empty ones, letters, and control characters. Spaces are fine.
+func checkPhraseSeparator(sep string) error {
+	if sep == "" {
+		return fmt.Errorf("-separator must not be empty in phrase mode")
+	}
+	for _, ch := range sep {
+		if unicode.IsLetter(ch) || unicode.Is(unicode.Mn, ch) {
+			return fmt.Errorf("-separator must not contain letters (%q) in phrase mode — they run into the words", ch)
+		}
+		if unicode.IsControl(ch) {
+			return fmt.Errorf("-separator contains a control character (%q)", ch)
 		}
-		parts[i] = string(seg)
 	}
-	return strings.Join(parts, cfg.Separator), nil
+	return nil
 }
 
 func generatePassphrase(cfg PassphraseConfig) (string, error) {
-	// Start with user's included words (preserve order)
+	pool := phraseWords(cfg)
+	if len(pool) == 0 {
+		return "", fmt.Errorf("no words of the requested length in the word list")
+	}
+	return drawFitting(cfg.MaxLength, func() (string, error) {
+		return drawPassphrase(cfg, pool)
+	})
+}
+
+// drawFitting calls draw until it returns a passphrase of at most maxLength
+// characters (any length when maxLength is 0).
+func drawFitting(maxLength int, draw func() (string, error)) (string, error) {
+	for attempt := 0; attempt < rejectionAttempts; attempt++ {
+		p, err := draw()
+		if err != nil {
+			return "", err
+		}
+		if maxLength == 0 || len(clusters(p)) <= maxLength {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("no passphrase fit in %d characters after %d attempts", maxLength, rejectionAttempts)
+}
+
+func drawPassphrase(cfg PassphraseConfig, pool []string) (string, error) {
+	// Start with user's included words, in order unless -include-anywhere
+	// shuffles them in below
 	words := make([]string, 0, cfg.Words)
 	for _, w := range cfg.Include {
-		w = strings.TrimSpace(w)
+		w = nfc(strings.TrimSpace(w))
 		if w == "" {
 			continue
 		}
@@ -291,39 +830,94 @@ func generatePassphrase(cfg PassphraseConfig) (string, error) {
 
 	// Fill remaining slots with random words (appended after user words)
 	for len(words) < cfg.Words {
-		idx, err := randInt(len(wordList))
+		idx, err := randInt(len(pool))
 		if err != nil {
 			return "", err
 		}
-		words = append(words, wordList[idx])
+		words = append(words, pool[idx])
+	}
+	if cfg.ShuffleInclude {
+		if err := shuffle(words); err != nil {
+			return "", err
+		}
 	}
+	return finishPhrase(cfg, words)
+}
 
-	// Apply capitalize and optional char shuffle
+// finishPhrase transforms each word, adds the number and joins the words
+// with separators.
+func finishPhrase(cfg PassphraseConfig, words []string) (string, error) {
+	// Apply optional char shuffle, case and leet to each word
 	for i, w := range words {
 		if cfg.ShuffleChars {
-			b := []byte(w)
-			if err := shuffleBytes(b); err != nil {
+			cs := clusters(w)
+			if err := shuffle(cs); err != nil {
+				return "", err
+			}
+			w = strings.Join(cs, "")
+		}
+		switch {
+		case cfg.RandomCase:
+			c, err := randInt(3)
+			if err != nil {
 				return "", err
 			}
-			w = string(b)
+			switch c {
+			case 0:
+				w = capitalizeWord(w)
+			case 1:
+				w = strings.ToUpper(w)
+			default:
+				w = strings.ToLower(w)
+			}
+		case cfg.Capitalize:
+			w = capitalizeWord(w)
 		}
-		if cfg.Capitalize && len(w) > 0 {
-			w = strings.ToUpper(w[:1]) + strings.ToLower(w[1:])
+		if cfg.Leet {
+			flip, err := randInt(2)
+			if err != nil {
+				return "", err
+			}
+			if flip == 1 {
+				w = leet(w)
+			}
 		}
 		words[i] = w
 	}
 
-	passphrase := strings.Join(words, cfg.Separator)
-
 	if cfg.AddNumber {
 		n, err := randInt(1000)
 		if err != nil {
 			return "", err
 		}
-		passphrase += cfg.Separator + strconv.Itoa(n)
+		pos := len(words)
+		if cfg.NumberAnywhere {
+			if pos, err = randInt(len(words) + 1); err != nil {
+				return "", err
+			}
+		}
+		words = slices.Insert(words, pos, strconv.Itoa(n))
+	}
+
+	// Join, drawing each separator from SeparatorSet when there is one
+	seps := []rune(cfg.SeparatorSet)
+	var sb strings.Builder
+	for i, w := range words {
+		if i > 0 {
+			if len(seps) > 0 {
+				idx, err := randInt(len(seps))
+				if err != nil {
+					return "", err
+				}
+				sb.WriteRune(seps[idx])
+			} else {
+				sb.WriteString(cfg.Separator)
+			}
+		}
+		sb.WriteString(w)
 	}
 
-	return passphrase, nil
+	return nfc(sb.String()), nil
 }
 
 func splitWords(raw string) []string {
@@ -419,6 +1013,29 @@ func askChoice(prompt string, choices []string, def string) string {
 	}
 }
 
+// askSeparator prompts until it gets a usable separator.
+func askSeparator(prompt, def string) string {
+	for {
+		fmt.Printf("%s [%s]: ", prompt, def)
+		line, _ := reader.ReadString('\n')
+		sep := strings.TrimRight(line, "\r\n")
+		if sep == "" {
+			return def
+		}
+		if err := checkSeparator(sep); err != nil {
+			fmt.Printf("  ✗  %v\n", err)
+			continue
+		}
+		return sep
+	}
+}
+
+// askLookalikes prompts for a lookalike preset and returns the characters to exclude.
+func askLookalikes() string {
+	level := askChoice("  Exclude lookalikes (none / ambiguous / homoglyphs)", []string{"none", "ambiguous", "homoglyphs"}, "none")
+	return lookalikes(level == "ambiguous", level == "homoglyphs")
+}
+
 func printDivider() { fmt.Println("  " + strings.Repeat("─", 44)) }
 
 func runInteractive() {
@@ -433,8 +1050,9 @@ func runInteractive() {
 	fmt.Println("    1  Random     e.g. X7&kP2!qL9mR@wZ")
 	fmt.Println("    2  Segmented  e.g. ab12-cd34-ef56")
 	fmt.Println("    3  Passphrase e.g. Tiger-Maple-Cloud-97")
+	fmt.Println("    4  Sentence   e.g. Brave-Otter-Juggles-Quietly-42")
 	fmt.Println()
-	typeChoice := askChoice("  Choose type", []string{"1", "2", "3"}, "1")
+	typeChoice := askChoice("  Choose type", []string{"1", "2", "3", "4"}, "1")
 	fmt.Println()
 
 	count := askInt("  How many passwords to generate", 1)
@@ -454,6 +1072,7 @@ func runInteractive() {
 		noDigits  := !askYesNo("  Include digits     (0-9)", true)
 		noSymbols := !askYesNo("  Include symbols    (!@#$...)", true)
 		excludeRaw := askDefault("  Exclude characters (leave blank to skip)", "")
+		excludeRaw += askLookalikes()
 		fmt.Println()
 
 		cfg := RandomConfig{
@@ -480,12 +1099,17 @@ func runInteractive() {
 		printDivider()
 		segments  := askInt("  Number of segments", 3)
 		segLength := askInt("  Characters per segment", 4)
-		separator := askChoice("  Separator  (- or _)", []string{"-", "_"}, "-")
+		separator := askSeparator("  Separator  (any text)", "-")
 		fmt.Println()
 		noUpper  := !askYesNo("  Include uppercase  (A-Z)", false)
 		noLower  := !askYesNo("  Include lowercase  (a-z)", true)
 		noDigits := !askYesNo("  Include digits     (0-9)", true)
+		symbols  := ""
+		if askYesNo("  Include symbols    (!@#$...)", false) {
+			symbols = charSymbols
+		}
 		excludeRaw := askDefault("  Exclude characters (leave blank to skip)", "")
+		excludeRaw += askLookalikes()
 		fmt.Println()
 
 		cfg := SegmentConfig{
@@ -495,6 +1119,7 @@ func runInteractive() {
 			NoUpper:   noUpper,
 			NoLower:   noLower,
 			NoDigits:  noDigits,
+			Symbols:   symbols,
 			Exclude:   excludeRaw,
 		}
 		for i := 0; i < count; i++ {
@@ -516,14 +1141,17 @@ func runInteractive() {
 		if includeRaw != "" {
 			include = splitWords(includeRaw)
 		}
-		defWords := 4
-		if len(include) > 0 {
-			defWords = len(include) // default to exactly their words, no extras
-		}
-		numWords := askInt("  Total number of words", defWords)
+		numWords := askInt("  Total number of words", defaultWords(len(include)))
 		if numWords < len(include) {
 			numWords = len(include)
 		}
+		if w := includeWarning(len(include), numWords); w != "" {
+			fmt.Println("  !  " + w)
+		}
+		includeAnywhere := false
+		if len(include) > 0 && numWords > len(include) {
+			includeAnywhere = askYesNo("  Place your words at random positions", true)
+		}
 		separator    := askChoice("  Separator  (- or _)", []string{"-", "_"}, "-")
 		capitalize   := askYesNo("  Capitalize each word", true)
 		shuffleChars := askYesNo("  Shuffle characters within each word", false)
@@ -531,12 +1159,13 @@ func runInteractive() {
 		fmt.Println()
 
 		cfg := PassphraseConfig{
-			Words:        numWords,
-			Separator:    separator,
-			Capitalize:   capitalize,
-			AddNumber:    addNumber,
-			Include:      include,
-			ShuffleChars: shuffleChars,
+			Words:          numWords,
+			Separator:      separator,
+			Capitalize:     capitalize,
+			AddNumber:      addNumber,
+			Include:        include,
+			ShuffleInclude: includeAnywhere,
+			ShuffleChars:   shuffleChars,
 		}
 		for i := 0; i < count; i++ {
 			p, err := generatePassphrase(cfg)
@@ -546,6 +1175,42 @@ func runInteractive() {
 			}
 			passwords = append(passwords, p)
 		}
+
+	case "4":
+		// ── Sentence ──
+		printDivider()
+		fmt.Println("  Sentence options")
+		printDivider()
+		var slots []string
+		for {
+			var err error
+			if slots, err = parseTemplate(askDefault("  Template", defaultTemplate)); err == nil {
+				break
+			}
+			fmt.Printf("  ✗  %v\n", err)
+		}
+		separator  := askChoice("  Separator  (- or _)", []string{"-", "_"}, "-")
+		capitalize := askYesNo("  Capitalize each word", true)
+		addNumber  := askYesNo("  Add a random number at end", true)
+		fmt.Println()
+
+		cfg := SentenceConfig{
+			Template: slots,
+			PassphraseConfig: PassphraseConfig{
+				Words:      len(slots),
+				Separator:  separator,
+				Capitalize: capitalize,
+				AddNumber:  addNumber,
+			},
+		}
+		for i := 0; i < count; i++ {
+			p, err := generateSentence(cfg)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "  error: %v\n", err)
+				os.Exit(1)
+			}
+			passwords = append(passwords, p)
+		}
 	}
 
 	// ── Output ──
@@ -598,6 +1263,65 @@ func runQuickSegment(separator string) {
 	}
 }
 
+// printShares splits secret and prints one share per line, exiting on error.
+func printShares(secret string, n, threshold int) {
+	shares, err := splitSecret([]byte(secret), n, threshold)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	for _, s := range shares {
+		fmt.Println(s)
+	}
+	fmt.Fprintf(os.Stderr, "Any %d of these %d shares recover the secret: passgen combine\n", threshold, n)
+}
+
+// passwordEntry is one password in -format json output.
+type passwordEntry struct {
+	Password string `json:"password"`
+	Hash     string `json:"hash,omitempty"`
+	User     string `json:"user,omitempty"`
+}
+
+// printPasswords writes the passwords to stdout, each with its hash when
+// hashes is set, in a user:hash line or SQL statement when user is.
+func printPasswords(passwords, hashes []string, scheme, user, format string) error {
+	if format == "json" {
+		entries := make([]passwordEntry, len(passwords))
+		for i, p := range passwords {
+			entries[i] = passwordEntry{Password: p, User: user}
+			if hashes != nil {
+				entries[i].Hash = hashes[i]
+			}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	}
+	for i, p := range passwords {
+		if hashes != nil && user != "" {
+			fmt.Printf("%s\t%s\n", p, accountLine(scheme, user, hashes[i]))
+		} else if hashes != nil {
+			fmt.Printf("%s\t%s\n", p, hashes[i])
+		} else {
+			fmt.Println(p)
+		}
+	}
+	return nil
+}
+
+// flagWasSet reports whether name was given explicitly on the command line.
+func flagWasSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
 func main() {
 	// No args → interactive
 	if len(os.Args) == 1 {
@@ -605,7 +1329,35 @@ func main() {
 		return
 	}
 
-	// Quick segmented mode: passgen - or passgen _
+	// Subcommands
+	args := os.Args[1:]
+	derive, split, store, export, key, batch := false, false, false, false, false, false
+	switch os.Args[1] {
+	case "bip39":
+		runBIP39(os.Args[2:])
+		return
+	case "combine":
+		runCombine(os.Args[2:])
+		return
+	case "batch":
+		batch, args = true, os.Args[2:]
+	case "derive":
+		derive, args = true, os.Args[2:]
+	case "split":
+		split, args = true, os.Args[2:]
+	case "store":
+		if len(os.Args) < 3 || os.Args[2] != "pass" {
+			fmt.Fprintln(os.Stderr, "usage: passgen store pass -path NAME [options]")
+			os.Exit(2)
+		}
+		store, args = true, os.Args[3:]
+	case "export":
+		export, args = true, os.Args[2:]
+	case "key":
+		key, args = true, os.Args[2:]
+	}
+
+	// Quick segmented mode: passgen - or passgen _
 	if len(os.Args) == 2 && (os.Args[1] == "-" || os.Args[1] == "_") {
 		runQuickSegment(os.Args[1])
 		return
@@ -613,7 +1365,7 @@ func main() {
 
 	fs := flag.NewFlagSet("passgen", flag.ExitOnError)
 
-	mode      := fs.String("type",      "random", "Password type: random, segment, or phrase")
+	mode      := fs.String("type",      "random", "Password type: random, segment, phrase, sentence, markov, bip39, or framework")
 	length    := fs.Int("length",       16,       "Password length (random mode)")
 	count     := fs.Int("count",        1,        "Number of passwords to generate")
 	noUpper   := fs.Bool("no-upper",    false,    "Exclude uppercase letters (A-Z)")
@@ -623,133 +1375,1039 @@ func main() {
 	exclude   := fs.String("exclude",   "",       "Characters to exclude")
 	segments  := fs.Int("segments",     3,        "Number of segments (segment mode)")
 	segLen    := fs.Int("seg-length",   4,        "Characters per segment (segment mode)")
-	separator := fs.String("separator", "-",      "Separator: - or _ (segment/phrase mode)")
+	separator := fs.String("separator", "-",      "Separator text (segment/phrase mode; no letters in phrase mode)")
 	noCopy    := fs.Bool("no-copy",     false,    "Skip copying to clipboard")
-	words     := fs.Int("words",        4,        "Number of words (phrase mode)")
+	words     := fs.Int("words",        4,        "Number of words (phrase mode, default 4 or the -include count + 2; bip39 mode: 12, 15, 18, 21 or 24, default 12)")
 	capitalize := fs.Bool("capitalize", true,     "Capitalize words (phrase mode)")
 	addNum    := fs.Bool("add-number",  true,     "Add random number at end (phrase mode)")
 	include      := fs.String("include",       "",    "Your words to mix in, comma/space separated (phrase mode)")
+	incAnywhere  := fs.Bool("include-anywhere", false, "Put -include words at random positions instead of first (phrase mode)")
 	shuffleChars := fs.Bool("shuffle-chars", false,  "Shuffle characters within each word (phrase mode)")
+	separators   := fs.String("separators",    "",    "Draw each separator at random from these digits/symbols, e.g. \"0123456789!@#$%\" (phrase mode)")
+	randomCase   := fs.Bool("random-case",     false, "Randomly Capitalize, UPPER or lower each word (phrase mode)")
+	leetWords    := fs.Bool("leet",            false, "Randomly apply leet substitutions (a→4, e→3, …) to each word (phrase mode)")
+	minWordLen   := fs.Int("min-word-len",     0,     "Shortest random word, 0 = no limit (phrase mode)")
+	maxWordLen   := fs.Int("max-word-len",     0,     "Longest random word, 0 = no limit (phrase mode)")
+	maxLength    := fs.Int("max-length",       0,     "Longest passphrase in characters, 0 = no limit (phrase mode)")
+	numAnywhere  := fs.Bool("number-anywhere", false, "Put the number at a random word boundary, not the end (phrase mode)")
+	template     := fs.String("template",      defaultTemplate, "Part-of-speech slots: adjective, noun, verb, adverb (sentence mode)")
+	order        := fs.Int("order",            3,     "Letters of context the model uses (markov mode)")
+	corpus       := fs.String("corpus",        "",    "Text file to train on instead of the built-in word lists (markov mode)")
+	minEntropy   := fs.Float64("min-entropy",  0,     "Refuse settings below this many bits; in markov mode, add words until reached (default 60 there)")
+	site         := fs.String("site",          "",    "Site the password is for, e.g. github.com (passgen derive)")
+	login        := fs.String("login",         "",    "Login or email at the site (passgen derive; a login: line with passgen store pass; the user name with passgen export and password manager formats)")
+	counter      := fs.Int("counter",          1,     "Bump to get a new password for the same site and login (passgen derive)")
+	kdf          := fs.String("kdf",           "argon2id", "Master password stretching: argon2id or scrypt (passgen derive)")
+	numShares    := fs.Int("shares",           5,     "Number of shares to make (passgen split)")
+	threshold    := fs.Int("threshold",        3,     "Shares needed to recover the secret (passgen split)")
+	splitStdin   := fs.Bool("stdin",           false, "Split a secret read from stdin instead of generating one (passgen split)")
+	showSecret   := fs.Bool("show-secret",     false, "Print the generated secret to stderr even when it isn't a terminal (passgen split)")
+	preset       := fs.String("preset",        "",    "Secret for a framework: django, rails-secret-key-base, laravel-app-key, fernet, jwt-hs256 or flask (framework mode)")
+	passPath     := fs.String("path",          "",    "Entry to write, e.g. work/db/prod (passgen store pass)")
+	passURL      := fs.String("url",           "",    "Add a url: line to the entry (passgen store pass), or the entries' URL (passgen export, password manager formats)")
+	force        := fs.Bool("force",           false, "Replace an existing entry or file (passgen store pass, passgen export, passgen batch)")
+	kdbxOut      := fs.String("kdbx",          "",    "KeePass database to write (passgen export)")
+	inFile       := fs.String("in",            "",    "CSV of accounts, one per password, with title, username, url and notes columns (passgen export, password manager formats), and type, profile and policy (passgen batch)")
+	outFile      := fs.String("out",           "",    "File to write the credentials to, created with mode 0600 (passgen batch)")
+	title        := fs.String("title",         "",    "Entry title, numbered with -count (passgen export, password manager formats; default \"Password\")")
+	notes        := fs.String("notes",         "",    "Entry notes (passgen export, password manager formats)")
+	kdbxCipher   := fs.String("cipher",        "aes256", "Database cipher: aes256 or chacha20 (passgen export)")
+	keyKind      := fs.String("kind",          "",    "Key type: wireguard, age, ssh-ed25519 or x25519 (passgen key)")
+	keyComment   := fs.String("comment",       "",    "Comment for the authorized_keys line (passgen key -kind ssh-ed25519)")
+	hashScheme   := fs.String("hash",          "",    "Also print each password's hash: "+hashSchemeNames)
+	hashParams   := fs.String("hash-params",   "",    "Hash costs, e.g. cost=12 (bcrypt), m=65536,t=3,p=4 (argon2id), ln=17,r=8,p=1 (scrypt), i=600000 (pbkdf2-sha256), rounds=5000 (sha512-crypt), ln=12,r=32 (yescrypt), i=4096 (pg-scram)")
+	user         := fs.String("user",          "",    "Print user:hash for chpasswd -e or htpasswd, or SQL creating this role for the pg-scram and mysql-* hashes")
+	format       := fs.String("format",        "plain", "Output format: plain (password, then a tab and the hash with -hash), json, or a password manager import: bitwarden-json, 1password-csv, lastpass-csv; csv or json for passgen batch (default csv)")
+	minUpper     := fs.Int("min-upper",     0,     "Minimum uppercase letters (random mode)")
+	minLower     := fs.Int("min-lower",     0,     "Minimum lowercase letters (random mode)")
+	minDigits    := fs.Int("min-digits",    0,     "Minimum digits (random mode)")
+	minSymbols   := fs.Int("min-symbols",   0,     "Minimum symbols (random mode)")
+	rules        := fs.String("rules",         "",    "Apple passwordrules policy, e.g. \"minlength: 12; required: upper; required: digit\" (random mode)")
+	noRepeat     := fs.Bool("no-repeat",       false, "No identical adjacent characters, e.g. aa (random/segment mode)")
+	maxConsec    := fs.Int("max-consecutive",  0,     "Longest run of one repeated character, 0 = unlimited (random/segment mode)")
+	noSequences  := fs.Bool("no-sequences",    false, "No runs like abc, cba, 123 (random/segment mode)")
+	noWalks      := fs.Bool("no-keyboard-walks", false, "No runs of adjacent keys like qwe, asd (random/segment mode)")
+	uniqueChars  := fs.Bool("unique-chars",    false, "Never use a character twice (random/segment mode)")
+	charset      := fs.String("charset",       "",    "Draw from exactly these characters, replacing all classes (random/segment mode)")
+	noAmbiguous  := fs.Bool("no-ambiguous",    false, "Exclude lookalikes like 0/O/o, 1/l/I/|, 5/S, 2/Z, 8/B (random/segment mode)")
+	noHomoglyphs := fs.Bool("no-homoglyphs",   false, "Stricter -no-ambiguous for printed/handwritten use (random/segment mode)")
+	symbols      := fs.String("symbols",       "",    "Symbol set: safe, ldap, xml, json, or literal characters (random/segment mode)")
+	unicodeRaw   := fs.String("unicode",       "",    "Extra character sets: latin1, greek, cyrillic, emoji, comma separated (random/segment mode)")
+	segSymbols   := fs.Bool("segment-symbols", false, "Include symbols in segments (segment mode)")
+	segLengths   := fs.String("seg-lengths",   "",    "Per-segment lengths like 4,6,4, overriding -segments/-seg-length (segment mode)")
+	segCover     := fs.String("seg-cover",     "",    "Guarantee every class appears: overall or per-segment (segment mode)")
+	segClasses   := fs.String("seg-classes",   "",    "Per-segment classes like d,ul,* — u upper, l lower, d digits, s symbols, * shared (segment mode)")
 
 	fs.Usage = func() {
 		fmt.Fprintln(os.Stderr, "passgen — Cryptographically secure password generator")
 		fmt.Fprintln(os.Stderr, "\nUsage:")
 		fmt.Fprintln(os.Stderr, "  passgen                                   Interactive mode")
 		fmt.Fprintln(os.Stderr, "  passgen [options]                         Flag mode")
+		fmt.Fprintln(os.Stderr, "  passgen derive -site SITE [options]       Same password every time from a master password")
+		fmt.Fprintln(os.Stderr, "  passgen bip39 validate                    Check a BIP39 mnemonic read from stdin")
+		fmt.Fprintln(os.Stderr, "  passgen split -shares N -threshold M      Generate a secret and split it into Shamir shares")
+		fmt.Fprintln(os.Stderr, "  passgen combine [SHARE...]                Recover a secret from shares (args or stdin lines)")
+		fmt.Fprintln(os.Stderr, "  passgen key -kind KIND                    Key pair: wireguard, age, ssh-ed25519 or x25519")
+		fmt.Fprintln(os.Stderr, "  passgen store pass -path NAME [options]   Generate a password into password-store (pass)")
+		fmt.Fprintln(os.Stderr, "  passgen export -kdbx FILE [options]       Generate passwords into a new KeePass database")
+		fmt.Fprintln(os.Stderr, "  passgen batch -in CSV -out FILE [options] A password per account, each row its own type")
 		fmt.Fprintln(os.Stderr, "\nOptions:")
 		fs.PrintDefaults()
 		fmt.Fprintln(os.Stderr, "\nExamples:")
 		fmt.Fprintln(os.Stderr, `  passgen -length 32`)
 		fmt.Fprintln(os.Stderr, `  passgen -count 5 -no-symbols`)
+		fmt.Fprintln(os.Stderr, `  passgen -length 14 -min-digits 2 -min-symbols 2`)
 		fmt.Fprintln(os.Stderr, `  passgen -exclude "0OIl1"`)
+		fmt.Fprintln(os.Stderr, `  passgen -no-ambiguous -exclude "#"`)
+		fmt.Fprintln(os.Stderr, `  passgen -symbols safe`)
+		fmt.Fprintln(os.Stderr, `  passgen -unicode greek,cyrillic`)
+		fmt.Fprintln(os.Stderr, `  passgen -charset "abcdef0123456789" -length 32`)
+		fmt.Fprintln(os.Stderr, `  passgen -rules "minlength: 12; required: lower; required: upper; required: digit; max-consecutive: 2"`)
+		fmt.Fprintln(os.Stderr, `  passgen -no-repeat -no-sequences -no-keyboard-walks`)
 		fmt.Fprintln(os.Stderr, `  passgen -type segment -segments 4 -seg-length 5`)
 		fmt.Fprintln(os.Stderr, `  passgen -type segment -separator _`)
+		fmt.Fprintln(os.Stderr, `  passgen -type segment -segment-symbols -separator " :: "`)
+		fmt.Fprintln(os.Stderr, `  passgen -type segment -seg-cover overall`)
+		fmt.Fprintln(os.Stderr, `  passgen -type segment -seg-lengths 5,5,5,5 -seg-classes u,ud,ud,d -no-lower`)
 		fmt.Fprintln(os.Stderr, `  passgen -type segment -segments 3 -seg-length 6 -no-copy`)
 		fmt.Fprintln(os.Stderr, `  passgen -type phrase`)
 		fmt.Fprintln(os.Stderr, `  passgen -type phrase -words 5 -separator _`)
 		fmt.Fprintln(os.Stderr, `  passgen -type phrase -capitalize=false -add-number=false`)
+		fmt.Fprintln(os.Stderr, `  passgen -type phrase -words 4 -max-length 24`)
+		fmt.Fprintln(os.Stderr, `  passgen -type phrase -separators "0123456789!@#$%" -random-case -leet -number-anywhere`)
 		fmt.Fprintln(os.Stderr, `  passgen -type phrase -include "tiger,coffee"`)
 		fmt.Fprintln(os.Stderr, `  passgen -type phrase -include "sun,moon" -words 5`)
+		fmt.Fprintln(os.Stderr, `  passgen -type sentence`)
+		fmt.Fprintln(os.Stderr, `  passgen -type sentence -template "adjective noun verb adjective noun" -separator " "`)
+		fmt.Fprintln(os.Stderr, `  passgen -type markov -min-entropy 70`)
+		fmt.Fprintln(os.Stderr, `  passgen -type markov -order 2 -corpus words.txt`)
+		fmt.Fprintln(os.Stderr, `  passgen -type bip39 -words 24`)
+		fmt.Fprintln(os.Stderr, `  passgen bip39 validate < mnemonic.txt`)
+		fmt.Fprintln(os.Stderr, `  passgen -type framework -preset django`)
+		fmt.Fprintln(os.Stderr, `  passgen -type framework -preset laravel-app-key`)
+		fmt.Fprintln(os.Stderr, `  passgen derive -site github.com -login ops@acme -counter 2`)
+		fmt.Fprintln(os.Stderr, `  passgen derive -site bank.example -rules "maxlength: 20; required: upper; required: digit"`)
+		fmt.Fprintln(os.Stderr, `  passgen -count 10 -hash bcrypt -hash-params cost=12`)
+		fmt.Fprintln(os.Stderr, `  passgen -hash argon2id -format json`)
+		fmt.Fprintln(os.Stderr, `  passgen -hash yescrypt -user deploy | cut -f2 | sudo chpasswd -e`)
+		fmt.Fprintln(os.Stderr, `  passgen -hash apr1 -user alice | cut -f2 >> .htpasswd`)
+		fmt.Fprintln(os.Stderr, `  passgen -hash pg-scram -user app_rw`)
+		fmt.Fprintln(os.Stderr, `  passgen -hash mysql-caching-sha2 -user 'app@10.0.%'`)
+		fmt.Fprintln(os.Stderr, `  passgen split -shares 5 -threshold 3 -length 32 > shares.txt`)
+		fmt.Fprintln(os.Stderr, `  passgen combine < shares.txt`)
+		fmt.Fprintln(os.Stderr, `  passgen key -kind wireguard`)
+		fmt.Fprintln(os.Stderr, `  passgen store pass -path work/db/prod -login app -url db.example.com`)
+		fmt.Fprintln(os.Stderr, `  passgen export -kdbx client.kdbx -in accounts.csv`)
+		fmt.Fprintln(os.Stderr, `  passgen -format bitwarden-json -in accounts.csv > import.json`)
+		fmt.Fprintln(os.Stderr, `  passgen batch -in contractors.csv -out credentials.csv -hash yescrypt`)
+		fmt.Fprintln(os.Stderr, `  passgen key -kind ssh-ed25519 -comment deploy@ci`)
 	}
 
-	fs.Parse(os.Args[1:])
+	fs.Parse(args)
 
-	var passwords []string
+	if *maxConsec < 0 {
+		fmt.Fprintln(os.Stderr, "error: -max-consecutive must be >= 0")
+		os.Exit(1)
+	}
+	constraints := Constraints{
+		MaxConsecutive:  *maxConsec,
+		NoSequences:     *noSequences,
+		NoKeyboardWalks: *noWalks,
+		UniqueChars:     *uniqueChars,
+	}
+	if *noRepeat {
+		constraints.MaxConsecutive = 1
+	}
+
+	// Compare user-supplied characters in composed form, so a decomposed
+	// é typed or pasted in still matches é
+	*exclude, *charset, *symbols, *include = nfc(*exclude), nfc(*charset), nfc(*symbols), nfc(*include)
+
+	// Lookalike presets compose with -exclude
+	*exclude += lookalikes(*noAmbiguous, *noHomoglyphs)
 
-	switch strings.ToLower(*mode) {
-	case "random":
-		if *length < 1 {
-			fmt.Fprintln(os.Stderr, "error: -length must be >= 1")
+	if *minEntropy < 0 {
+		fmt.Fprintln(os.Stderr, "error: -min-entropy must be >= 0")
+		os.Exit(1)
+	}
+
+	// prepare checks the flags that passgen batch rows can override —
+	// -type, -symbols, -rules and -preset — and resolves the characters
+	// they choose
+	var symbolSet string
+	var unicodeExtra []string
+	prepare := func() error {
+		if *rules != "" && strings.ToLower(*mode) != "random" {
+			return fmt.Errorf("-rules only applies to random mode")
+		}
+		if *charset != "" {
+			if err := checkCharset("-charset", *charset); err != nil {
+				return err
+			}
+			if *noUpper || *noLower || *noDigits || *noSymbols || *symbols != "" || *segSymbols || *rules != "" {
+				return fmt.Errorf("-charset replaces the character classes — it can't be combined with -no-*, -symbols, -segment-symbols or -rules")
+			}
+			*charset = string(uniqueRunes(*charset))
+		}
+		symbolSet = ""
+		if *symbols != "" {
+			var err error
+			if symbolSet, err = resolveSymbols(*symbols); err != nil {
+				return err
+			}
+		}
+		unicodeExtra = nil
+		if *unicodeRaw != "" {
+			if *charset != "" || *rules != "" {
+				return fmt.Errorf("-unicode adds to the built-in classes — it can't be combined with -charset or -rules")
+			}
+			var err error
+			if unicodeExtra, err = parseUnicodeSets(*unicodeRaw); err != nil {
+				return err
+			}
+		}
+		if strings.ToLower(*mode) != "markov" && (flagWasSet(fs, "order") || *corpus != "") {
+			return fmt.Errorf("-order and -corpus apply to markov mode only")
+		}
+		if strings.ToLower(*mode) != "framework" && *preset != "" {
+			return fmt.Errorf("-preset applies to framework mode only — add -type framework")
+		}
+		return nil
+	}
+	if !batch {
+		if err := prepare(); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
 			os.Exit(1)
 		}
-		cfg := RandomConfig{
-			Length:    *length,
-			NoUpper:   *noUpper,
-			NoLower:   *noLower,
-			NoDigits:  *noDigits,
-			NoSymbols: *noSymbols,
-			Exclude:   *exclude,
-		}
-		for i := 0; i < *count; i++ {
-			p, err := generateRandom(cfg)
-			if err != nil {
+	}
+	// checkEntropy refuses settings whose estimate falls short of -min-entropy
+	checkEntropy := func(bits float64) error {
+		if bits < *minEntropy {
+			return fmt.Errorf("these settings give ~%.1f bits, below -min-entropy %g", bits, *minEntropy)
+		}
+		return nil
+	}
+
+	*format = strings.ToLower(*format)
+	if batch {
+		if !flagWasSet(fs, "format") {
+			*format = "csv"
+		}
+		if *format != "csv" && *format != "json" {
+			fmt.Fprintf(os.Stderr, "error: passgen batch writes -format csv or json, not %q\n", *format)
+			os.Exit(1)
+		}
+	} else if *format != "plain" && *format != "json" && vaultFormats[*format] == nil {
+		fmt.Fprintf(os.Stderr, "error: -format must be plain, json, bitwarden-json, 1password-csv or lastpass-csv, not %q\n", *format)
+		os.Exit(1)
+	}
+	vault := vaultFormats[*format] != nil
+
+	if batch {
+		if *inFile == "" || *outFile == "" {
+			fmt.Fprintln(os.Stderr, "usage: passgen batch -in accounts.csv -out credentials.csv [options]")
+			os.Exit(2)
+		}
+		if flagWasSet(fs, "count") || *title != "" || *notes != "" || *user != "" {
+			fmt.Fprintln(os.Stderr, "error: passgen batch makes a password per -in row — -count, -title, -notes and -user don't apply")
+			os.Exit(1)
+		}
+		if _, err := os.Stat(*outFile); err == nil && !*force {
+			fmt.Fprintf(os.Stderr, "error: %s already exists — use -force to replace it\n", *outFile)
+			os.Exit(1)
+		}
+	} else if *outFile != "" {
+		fmt.Fprintln(os.Stderr, "error: -out applies to passgen batch only")
+		os.Exit(1)
+	}
+
+	if key {
+		fs.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "kind", "comment", "format", "no-copy":
+			default:
+				fmt.Fprintf(os.Stderr, "error: -%s doesn't apply to passgen key\n", f.Name)
+				os.Exit(1)
+			}
+		})
+		if vault {
+			fmt.Fprintln(os.Stderr, "error: passgen key prints -format plain or json, not a password manager import")
+			os.Exit(1)
+		}
+		if err := runKey(*keyKind, *keyComment, *format, *noCopy); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	} else if *keyKind != "" || *keyComment != "" {
+		fmt.Fprintln(os.Stderr, "error: -kind and -comment apply to passgen key only")
+		os.Exit(1)
+	}
+
+	if derive {
+		switch strings.ToLower(*mode) {
+		case "random", "segment", "phrase", "passphrase":
+		default:
+			fmt.Fprintln(os.Stderr, "error: passgen derive renders random, segment and phrase passwords only")
+			os.Exit(1)
+		}
+		if *site == "" {
+			fmt.Fprintln(os.Stderr, "error: passgen derive needs -site")
+			os.Exit(1)
+		}
+		if flagWasSet(fs, "count") {
+			fmt.Fprintln(os.Stderr, "error: passgen derive makes one password — use -counter for another")
+			os.Exit(1)
+		}
+		if *counter < 1 {
+			fmt.Fprintln(os.Stderr, "error: -counter must be >= 1")
+			os.Exit(1)
+		}
+		if _, ok := deriveKDFs[*kdf]; !ok {
+			fmt.Fprintf(os.Stderr, "error: -kdf must be argon2id or scrypt, not %q\n", *kdf)
+			os.Exit(1)
+		}
+		master, err := readSecret("Master password: ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		key, err := deriveKey(master, *site, *login, *counter, *kdf)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		if randSource, err = newKeystream(key); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+	} else if *site != "" || flagWasSet(fs, "counter") || flagWasSet(fs, "kdf") {
+		fmt.Fprintln(os.Stderr, "error: -site, -counter and -kdf apply to passgen derive only")
+		os.Exit(1)
+	} else if *login != "" && !store && !export && !vault {
+		fmt.Fprintln(os.Stderr, "error: -login applies to passgen derive, passgen store pass, passgen export and the password manager formats only")
+		os.Exit(1)
+	}
+
+	var passFile string
+	if store {
+		if flagWasSet(fs, "count") {
+			fmt.Fprintln(os.Stderr, "error: passgen store pass writes one entry")
+			os.Exit(1)
+		}
+		if strings.ContainsAny(*login+*passURL, "\r\n") {
+			fmt.Fprintln(os.Stderr, "error: -login and -url can't contain a line break")
+			os.Exit(1)
+		}
+		var err error
+		if passFile, err = checkPassEntry(*passPath, *force); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+	} else if *passPath != "" {
+		fmt.Fprintln(os.Stderr, "error: -path applies to passgen store pass only")
+		os.Exit(1)
+	} else if *force && !export && !batch {
+		fmt.Fprintln(os.Stderr, "error: -force applies to passgen store pass, passgen export and passgen batch only")
+		os.Exit(1)
+	} else if *passURL != "" && !export && !vault {
+		fmt.Fprintln(os.Stderr, "error: -url applies to passgen store pass, passgen export and the password manager formats only")
+		os.Exit(1)
+	}
+
+	if vault && (*hashScheme != "" || *user != "" || store) {
+		fmt.Fprintf(os.Stderr, "error: -format %s makes an import file — -hash, -user and passgen store pass don't apply\n", *format)
+		os.Exit(1)
+	}
+	if derive && *inFile != "" {
+		fmt.Fprintln(os.Stderr, "error: passgen derive makes one password — -in doesn't apply")
+		os.Exit(1)
+	}
+
+	// The accounts the passwords are for, from -in or the flags
+	var accounts []Account
+	if export || vault {
+		if *inFile != "" {
+			if flagWasSet(fs, "count") || *title != "" || *login != "" || *passURL != "" || *notes != "" {
+				fmt.Fprintln(os.Stderr, "error: -in lists the accounts — -count, -title, -login, -url and -notes don't apply")
+				os.Exit(1)
+			}
+			var err error
+			if accounts, err = readAccounts(*inFile, false); err != nil {
 				fmt.Fprintf(os.Stderr, "error: %v\n", err)
 				os.Exit(1)
 			}
-			passwords = append(passwords, p)
+			*count = len(accounts)
+		} else {
+			if *count < 1 {
+				fmt.Fprintln(os.Stderr, "error: -count must be >= 1")
+				os.Exit(1)
+			}
+			name := *title
+			if name == "" {
+				name = "Password"
+			}
+			for i := 1; i <= *count; i++ {
+				a := Account{Title: name, Username: *login, URL: *passURL, Notes: *notes}
+				if *count > 1 {
+					a.Title = fmt.Sprintf("%s %d", name, i)
+				}
+				accounts = append(accounts, a)
+			}
 		}
+	} else if *inFile != "" && !batch || *title != "" || *notes != "" {
+		fmt.Fprintln(os.Stderr, "error: -in, -title and -notes apply to passgen export and the password manager formats only")
+		os.Exit(1)
+	}
 
-	case "segment":
-		if *separator != "-" && *separator != "_" {
-			fmt.Fprintln(os.Stderr, "error: -separator must be - or _")
+	var kdbxMaster string
+	if export {
+		if *kdbxOut == "" {
+			fmt.Fprintln(os.Stderr, "error: passgen export needs -kdbx FILE")
 			os.Exit(1)
 		}
-		if *segments < 1 {
-			fmt.Fprintln(os.Stderr, "error: -segments must be >= 1")
+		if *hashScheme != "" || *user != "" || flagWasSet(fs, "format") {
+			fmt.Fprintln(os.Stderr, "error: passgen export writes a database — -hash, -user and -format don't apply")
 			os.Exit(1)
 		}
-		if *segLen < 1 {
-			fmt.Fprintln(os.Stderr, "error: -seg-length must be >= 1")
+		if _, ok := kdbxCiphers[*kdbxCipher]; !ok {
+			fmt.Fprintf(os.Stderr, "error: -cipher must be aes256 or chacha20, not %q\n", *kdbxCipher)
 			os.Exit(1)
 		}
-		cfg := SegmentConfig{
-			Segments:  *segments,
-			SegLength: *segLen,
-			Separator: *separator,
-			NoUpper:   *noUpper,
-			NoLower:   *noLower,
-			NoDigits:  *noDigits,
-			Exclude:   *exclude,
-		}
-		for i := 0; i < *count; i++ {
-			p, err := generateSegmented(cfg)
+		if _, err := os.Stat(*kdbxOut); err == nil && !*force {
+			fmt.Fprintf(os.Stderr, "error: %s already exists — use -force to replace it\n", *kdbxOut)
+			os.Exit(1)
+		}
+		var err error
+		if kdbxMaster, err = readSecret("Master password for the database: "); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		// A typo here would lock everyone out, so ask twice when typed
+		if stdinIsTerminal() {
+			again, err := readSecret("Repeat it: ")
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "error: %v\n", err)
 				os.Exit(1)
 			}
-			passwords = append(passwords, p)
+			if again != kdbxMaster {
+				fmt.Fprintln(os.Stderr, "error: the master passwords don't match")
+				os.Exit(1)
+			}
 		}
+	} else if *kdbxOut != "" || flagWasSet(fs, "cipher") {
+		fmt.Fprintln(os.Stderr, "error: -kdbx and -cipher apply to passgen export only")
+		os.Exit(1)
+	}
 
-	case "phrase", "passphrase":
-		var inc []string
-		if *include != "" {
-			inc = splitWords(*include)
+	if split {
+		if flagWasSet(fs, "count") {
+			fmt.Fprintln(os.Stderr, "error: passgen split splits one secret")
+			os.Exit(1)
+		}
+		if *threshold < 2 || *numShares < *threshold || *numShares > 255 {
+			fmt.Fprintln(os.Stderr, "error: need 2 <= -threshold <= -shares <= 255")
+			os.Exit(1)
 		}
-		if *words < len(inc) {
-			*words = len(inc)
+		if *splitStdin {
+			data, err := io.ReadAll(os.Stdin)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+				os.Exit(1)
+			}
+			printShares(strings.TrimRight(string(data), "\r\n"), *numShares, *threshold)
+			return
 		}
-		if *words < 1 {
-			fmt.Fprintln(os.Stderr, "error: -words must be >= 1")
+	} else if flagWasSet(fs, "shares") || flagWasSet(fs, "threshold") || *splitStdin || *showSecret {
+		fmt.Fprintln(os.Stderr, "error: -shares, -threshold, -stdin and -show-secret apply to passgen split only")
+		os.Exit(1)
+	}
+
+	var hashCfg HashConfig
+	if *hashScheme != "" {
+		var err error
+		if hashCfg, err = parseHashConfig(*hashScheme, *hashParams); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
 			os.Exit(1)
 		}
-		// If user provides words but didn't change -words, default to their count
-		if len(inc) > 0 && *words == 4 {
-			*words = len(inc)
+	} else if *hashParams != "" {
+		fmt.Fprintln(os.Stderr, "error: -hash-params needs -hash")
+		os.Exit(1)
+	}
+	if *user != "" {
+		switch hashCfg.Scheme {
+		case "bcrypt", "sha512-crypt", "yescrypt", "apr1":
+			if strings.Contains(*user, ":") {
+				fmt.Fprintln(os.Stderr, "error: -user can't contain ':' in a user:hash line")
+				os.Exit(1)
+			}
+		case "mysql-native", "mysql-caching-sha2":
+			if strings.Contains(*user, `\`) {
+				fmt.Fprintln(os.Stderr, "error: -user can't contain '\\' — MySQL reads it differently with and without NO_BACKSLASH_ESCAPES")
+				os.Exit(1)
+			}
+		case "pg-scram":
+		default:
+			fmt.Fprintln(os.Stderr, "error: -user makes user:hash lines or SQL — use it with -hash bcrypt, sha512-crypt, yescrypt, apr1, pg-scram, mysql-native or mysql-caching-sha2")
+			os.Exit(1)
 		}
-		cfg := PassphraseConfig{
-			Words:        *words,
-			Separator:    *separator,
-			Capitalize:   *capitalize,
-			AddNumber:    *addNum,
-			Include:      inc,
-			ShuffleChars: *shuffleChars,
-		}
-		for i := 0; i < *count; i++ {
-			p, err := generatePassphrase(cfg)
+		if strings.ContainsAny(*user, "\x00\r\n") {
+			fmt.Fprintln(os.Stderr, "error: -user can't contain a line break")
+			os.Exit(1)
+		}
+		if *count != 1 {
+			fmt.Fprintln(os.Stderr, "error: -user names one account — leave -count at 1")
+			os.Exit(1)
+		}
+	}
+	if split && (*hashScheme != "" || flagWasSet(fs, "format")) {
+		fmt.Fprintln(os.Stderr, "error: passgen split prints shares only — -hash and -format don't apply")
+		os.Exit(1)
+	}
+
+	var passwords []string
+	var entropyLine string
+
+	var entropyBits float64
+
+	// generate makes -count passwords of -type; passgen batch calls it once
+	// per row
+	generate := func() error {
+		passwords = nil
+		switch strings.ToLower(*mode) {
+		case "random":
+			if *length < 1 {
+				return fmt.Errorf("-length must be >= 1")
+			}
+			if *minUpper < 0 || *minLower < 0 || *minDigits < 0 || *minSymbols < 0 {
+				return fmt.Errorf("-min-upper, -min-lower, -min-digits and -min-symbols must be >= 0")
+			}
+			if sum := *minUpper + *minLower + *minDigits + *minSymbols; sum > *length && *rules == "" {
+				return fmt.Errorf("minimums add up to %d characters but -length is %d", sum, *length)
+			}
+			cfg := RandomConfig{
+				Length:      *length,
+				NoUpper:     *noUpper,
+				NoLower:     *noLower,
+				NoDigits:    *noDigits,
+				NoSymbols:   *noSymbols,
+				Exclude:     *exclude,
+				MinUpper:    *minUpper,
+				MinLower:    *minLower,
+				MinDigits:   *minDigits,
+				MinSymbols:  *minSymbols,
+				Symbols:     symbolSet,
+				Unicode:     unicodeExtra,
+				Charset:     *charset,
+				Constraints: constraints,
+			}
+			if *charset != "" && *minUpper+*minLower+*minDigits+*minSymbols > 0 {
+				return fmt.Errorf("-min-* apply to the built-in classes, not to -charset")
+			}
+			if *rules != "" {
+				if *noUpper || *noLower || *noDigits || *noSymbols || *symbols != "" || *minUpper+*minLower+*minDigits+*minSymbols > 0 {
+					return fmt.Errorf("-rules sets the character classes — use -exclude instead of -no-*, -min-* or -symbols")
+				}
+				pr, err := parsePasswordRules(*rules)
+				if err != nil {
+					return err
+				}
+				n := *length
+				if !flagWasSet(fs, "length") {
+					n = pr.clampLength(n)
+				}
+				if cfg, err = pr.randomConfig(n, *exclude); err != nil {
+					return err
+				}
+				// Keep the stricter of the rules' and the flags' max-consecutive
+				cfg.Constraints = constraints
+				if m := cfg.MaxConsecutive; m > 0 && (constraints.MaxConsecutive == 0 || m < constraints.MaxConsecutive) {
+					cfg.Constraints.MaxConsecutive = m
+				}
+			}
+			bits, cost, err := randomEntropy(cfg)
+			if err != nil {
+				return err
+			}
+			if err := checkEntropy(bits); err != nil {
+				return err
+			}
+			entropyBits = bits
+			entropyLine = entropyNote(bits, entropyCost{"constraints", cost})
+			for i := 0; i < *count; i++ {
+				p, err := generateRandom(cfg)
+				if err != nil {
+					return err
+				}
+				passwords = append(passwords, p)
+			}
+
+		case "segment":
+			if err := checkSeparator(*separator); err != nil {
+				return err
+			}
+			if *segments < 1 {
+				return fmt.Errorf("-segments must be >= 1")
+			}
+			if *segLen < 1 {
+				return fmt.Errorf("-seg-length must be >= 1")
+			}
+			var lengths []int
+			if *segLengths != "" {
+				var err error
+				if lengths, err = parseSegLengths(*segLengths); err != nil {
+					return err
+				}
+			}
+			var classes []string
+			if *segClasses != "" {
+				var err error
+				if classes, err = parseSegClasses(*segClasses); err != nil {
+					return err
+				}
+			}
+			switch *segCover {
+			case "", "overall", "per-segment":
+			default:
+				return fmt.Errorf("-seg-cover must be overall or per-segment")
+			}
+			if *segCover != "" && *charset != "" && len(classes) == 0 {
+				return fmt.Errorf("-seg-cover needs the built-in classes, not -charset")
+			}
+			// -symbols on its own is enough to ask for symbols in segments
+			segSymbolSet := ""
+			if *segSymbols || symbolSet != "" {
+				segSymbolSet = charSymbols
+				if symbolSet != "" {
+					segSymbolSet = symbolSet
+				}
+			}
+			cfg := SegmentConfig{
+				Segments:    *segments,
+				SegLength:   *segLen,
+				Lengths:     lengths,
+				Separator:   *separator,
+				NoUpper:     *noUpper,
+				NoLower:     *noLower,
+				NoDigits:    *noDigits,
+				Symbols:     segSymbolSet,
+				Unicode:     unicodeExtra,
+				Exclude:     *exclude,
+				Charset:     *charset,
+				Classes:     classes,
+				Coverage:    *segCover,
+				Constraints: constraints,
+			}
+			bits, costs, err := segmentEntropy(cfg)
 			if err != nil {
+				return err
+			}
+			if err := checkEntropy(bits); err != nil {
+				return err
+			}
+			entropyBits = bits
+			entropyLine = entropyNote(bits, costs...)
+			for i := 0; i < *count; i++ {
+				p, err := generateSegmented(cfg)
+				if err != nil {
+					return err
+				}
+				passwords = append(passwords, p)
+			}
+
+		case "phrase", "passphrase", "sentence", "markov":
+			if *noAmbiguous || *noHomoglyphs {
+				return fmt.Errorf("-no-ambiguous and -no-homoglyphs apply to random and segment modes only")
+			}
+			if constraints.active() {
+				return fmt.Errorf("character constraints apply to random and segment modes only")
+			}
+			if *charset != "" || *symbols != "" || *segSymbols || *unicodeRaw != "" {
+				return fmt.Errorf("-charset, -symbols, -segment-symbols and -unicode apply to random and segment modes only")
+			}
+			wordMode := strings.ToLower(*mode)
+			if wordMode != "phrase" && wordMode != "passphrase" {
+				if flagWasSet(fs, "words") || *include != "" || *incAnywhere || *shuffleChars || *minWordLen != 0 || *maxWordLen != 0 {
+					return fmt.Errorf("-words, -include, -include-anywhere, -shuffle-chars and the word-length bounds apply to phrase mode only, not %s mode", wordMode)
+				}
+			}
+			if wordMode != "sentence" && flagWasSet(fs, "template") {
+				return fmt.Errorf("-template applies to sentence mode only")
+			}
+			var slots []string
+			switch wordMode {
+			case "sentence":
+				var err error
+				if slots, err = parseTemplate(*template); err != nil {
+					return err
+				}
+				*words = len(slots)
+			case "markov":
+				if *maxLength != 0 {
+					return fmt.Errorf("-max-length doesn't apply to markov mode — words are added until -min-entropy is reached")
+				}
+			}
+			var inc []string
+			if *include != "" {
+				inc = splitWords(*include)
+			}
+			if wordMode == "phrase" || wordMode == "passphrase" {
+				if flagWasSet(fs, "words") {
+					if *words < len(inc) {
+						return fmt.Errorf("-words %d is fewer than the %d -include words", *words, len(inc))
+					}
+				} else {
+					*words = defaultWords(len(inc))
+				}
+			}
+			if *words < 1 {
+				return fmt.Errorf("-words must be >= 1")
+			}
+			if err := checkPhraseSeparator(*separator); err != nil {
+				return err
+			}
+			if *minWordLen < 0 || *maxWordLen < 0 || *maxLength < 0 {
+				return fmt.Errorf("-min-word-len, -max-word-len and -max-length must be >= 0")
+			}
+			if *maxWordLen > 0 && *minWordLen > *maxWordLen {
+				return fmt.Errorf("-min-word-len is greater than -max-word-len")
+			}
+			if *separators != "" {
+				if err := checkCharset("-separators", *separators); err != nil {
+					return err
+				}
+				if strings.IndexFunc(*separators, unicode.IsLetter) >= 0 {
+					return fmt.Errorf("-separators must not contain letters — they would run into the words")
+				}
+			}
+			cfg := PassphraseConfig{
+				Words:          *words,
+				Separator:      *separator,
+				SeparatorSet:   string(uniqueRunes(*separators)),
+				Capitalize:     *capitalize,
+				RandomCase:     *randomCase,
+				Leet:           *leetWords,
+				AddNumber:      *addNum,
+				NumberAnywhere: *numAnywhere,
+				Include:        inc,
+				ShuffleInclude: *incAnywhere,
+				ShuffleChars:   *shuffleChars,
+				MinWordLen:     *minWordLen,
+				MaxWordLen:     *maxWordLen,
+				MaxLength:      *maxLength,
+			}
+			switch wordMode {
+			case "sentence":
+				scfg := SentenceConfig{Template: slots, PassphraseConfig: cfg}
+				bits, costs, err := sentenceEntropy(scfg)
+				if err != nil {
+					return err
+				}
+				if err := checkEntropy(bits); err != nil {
+					return err
+				}
+				entropyBits = bits
+				entropyLine = entropyNote(bits, costs...)
+				for i := 0; i < *count; i++ {
+					p, err := generateSentence(scfg)
+					if err != nil {
+						return err
+					}
+					passwords = append(passwords, p)
+				}
+			case "markov":
+				training := markovCorpus()
+				if *corpus != "" {
+					var err error
+					if training, err = readCorpus(*corpus); err != nil {
+						return err
+					}
+				}
+				model, err := trainMarkov(training, *order)
+				if err != nil {
+					return err
+				}
+				mcfg := MarkovConfig{MinEntropy: *minEntropy, PassphraseConfig: cfg}
+				if mcfg.MinEntropy == 0 {
+					mcfg.MinEntropy = defaultMarkovEntropy
+				}
+				lowest := math.Inf(1)
+				for i := 0; i < *count; i++ {
+					p, bits, err := generateMarkov(model, mcfg)
+					if err != nil {
+						return err
+					}
+					passwords = append(passwords, p)
+					lowest = math.Min(lowest, bits)
+				}
+				entropyBits = lowest
+				if len(passwords) == 1 {
+					entropyLine = entropyNote(lowest)
+				} else if len(passwords) > 1 {
+					entropyLine = fmt.Sprintf("Entropy: ~%.1f bits (lowest of %d)", lowest, len(passwords))
+				}
+			default:
+				bits, costs, err := passphraseEntropy(cfg)
+				if err != nil {
+					return err
+				}
+				if err := checkEntropy(bits); err != nil {
+					return err
+				}
+				entropyBits = bits
+				entropyLine = entropyNote(bits, costs...)
+				if w := includeWarning(len(inc), *words); w != "" {
+					fmt.Fprintln(os.Stderr, "warning: "+w)
+				}
+				for i := 0; i < *count; i++ {
+					p, err := generatePassphrase(cfg)
+					if err != nil {
+						return err
+					}
+					passwords = append(passwords, p)
+				}
+			}
+
+		case "bip39":
+			// A mnemonic is exactly its words: any option that would change
+			// them makes it unreadable by wallets
+			var stray string
+			fs.Visit(func(f *flag.Flag) {
+				switch f.Name {
+				case "type", "words", "count", "no-copy", "min-entropy", "format", "in", "out", "force", "title", "login", "url", "notes", "shares", "threshold", "show-secret":
+				default:
+					if stray == "" {
+						stray = f.Name
+					}
+				}
+			})
+			if stray != "" {
+				return fmt.Errorf("-%s doesn't apply to bip39 mode", stray)
+			}
+			n := 12
+			if flagWasSet(fs, "words") {
+				n = *words
+			}
+			size, err := bip39EntropyBytes(n)
+			if err != nil {
+				return err
+			}
+			if err := checkEntropy(float64(size * 8)); err != nil {
+				return err
+			}
+			entropyBits = float64(size * 8)
+			entropyLine = entropyNote(float64(size * 8))
+			for i := 0; i < *count; i++ {
+				p, err := generateBIP39(n)
+				if err != nil {
+					return err
+				}
+				passwords = append(passwords, p)
+			}
+
+		case "framework":
+			// The framework fixes the secret's shape, so nothing may change it
+			var stray string
+			fs.Visit(func(f *flag.Flag) {
+				switch f.Name {
+				case "type", "preset", "count", "no-copy", "min-entropy", "format", "in", "out", "force", "title", "login", "url", "notes", "shares", "threshold", "show-secret":
+				default:
+					if stray == "" {
+						stray = f.Name
+					}
+				}
+			})
+			if stray != "" {
+				return fmt.Errorf("-%s doesn't apply to framework mode", stray)
+			}
+			p, err := lookupPreset(*preset)
+			if err != nil {
+				return err
+			}
+			if err := checkEntropy(p.bits); err != nil {
+				return err
+			}
+			entropyBits = p.bits
+			entropyLine = entropyNote(p.bits)
+			for i := 0; i < *count; i++ {
+				secret, err := p.generate()
+				if err != nil {
+					return err
+				}
+				passwords = append(passwords, secret)
+			}
+
+		default:
+			return fmt.Errorf("unknown type %q — use random, segment, phrase, sentence, markov, bip39, or framework", *mode)
+		}
+
+		return nil
+	}
+
+	if batch {
+		// Each row's type, profile and policy stand in for -type, -symbols
+		// (-preset in framework rows) and -rules; the other flags apply to
+		// every row
+		accounts, err := readAccounts(*inFile, true)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		defMode, defSymbols, defRules, defPreset := *mode, *symbols, *rules, *preset
+		var creds []batchCredential
+		var skipped []string
+		lowest := math.Inf(1)
+		for _, a := range accounts {
+			*mode, *symbols, *rules, *preset = defMode, defSymbols, defRules, defPreset
+			if a.Type != "" {
+				*mode = a.Type
+			}
+			if a.Policy != "" {
+				*rules = a.Policy
+			}
+			if a.Profile != "" {
+				if strings.ToLower(*mode) == "framework" {
+					*preset = a.Profile
+				} else {
+					*symbols = nfc(a.Profile)
+				}
+			}
+			c := batchCredential{Title: a.Title, Username: a.Username, URL: a.URL, Notes: a.Notes}
+			err := fmt.Errorf("no title or username")
+			if a.Title != "" || a.Username != "" {
+				err = prepare()
+			}
+			if err == nil {
+				err = generate()
+			}
+			if err == nil {
+				c.Password = passwords[0]
+				if hashCfg.Scheme != "" {
+					c.Hash, err = hashPassword(c.Password, hashCfg)
+				}
+			}
+			if err != nil {
+				name := a.Title
+				if name == "" {
+					name = a.Username
+				}
+				if name != "" {
+					name = " (" + name + ")"
+				}
+				skipped = append(skipped, fmt.Sprintf("line %d%s: %v", a.Line, name, err))
+				continue
+			}
+			creds = append(creds, c)
+			lowest = math.Min(lowest, entropyBits)
+		}
+		if len(creds) > 0 {
+			if err := writeBatch(*outFile, *format, *force, creds, hashCfg.Scheme != ""); err != nil {
 				fmt.Fprintf(os.Stderr, "error: %v\n", err)
 				os.Exit(1)
 			}
-			passwords = append(passwords, p)
+			fmt.Fprintf(os.Stderr, "Wrote %d of %d accounts to %s.\n", len(creds), len(accounts), *outFile)
+			fmt.Fprintf(os.Stderr, "Entropy: ~%.1f bits (lowest of %d)\n", lowest, len(creds))
+		}
+		if len(skipped) > 0 {
+			fmt.Fprintf(os.Stderr, "Skipped %d of %d rows:\n", len(skipped), len(accounts))
+			for _, s := range skipped {
+				fmt.Fprintln(os.Stderr, "  "+s)
+			}
+			os.Exit(1)
 		}
+		return
+	}
 
-	default:
-		fmt.Fprintf(os.Stderr, "error: unknown type %q — use random, segment, or phrase\n", *mode)
+	if err := generate(); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
 		os.Exit(1)
 	}
 
-	for _, p := range passwords {
-		fmt.Println(p)
+	if split {
+		// The shares go to stdout; the secret itself only to the terminal,
+		// not to a log or file stderr is redirected to
+		if entropyLine != "" {
+			fmt.Fprintln(os.Stderr, entropyLine)
+		}
+		if stderrIsTerminal() || *showSecret {
+			fmt.Fprintf(os.Stderr, "Secret: %s\n", passwords[0])
+		} else {
+			fmt.Fprintln(os.Stderr, "Secret not shown: stderr isn't a terminal (use -show-secret to print it)")
+		}
+		printShares(passwords[0], *numShares, *threshold)
+		return
+	}
+
+	if store {
+		// pass keeps the password on the first line, metadata after it
+		entry := passwords[0] + "\n"
+		if *login != "" {
+			entry += "login: " + *login + "\n"
+		}
+		if *passURL != "" {
+			entry += "url: " + *passURL + "\n"
+		}
+		if err := storePass(*passPath, passFile, entry); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Stored in the password store as %s.\n", *passPath)
+	}
+
+	if export {
+		// The passwords go into the database only, not to the terminal
+		name := strings.TrimSuffix(filepath.Base(*kdbxOut), filepath.Ext(*kdbxOut))
+		db, err := kdbxDatabase(kdbxMaster, *kdbxCipher, name, accounts, passwords)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := writePrivateFile(*kdbxOut, db, *force); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(passwords) == 1 {
+			fmt.Fprintf(os.Stderr, "Wrote 1 entry to %s.\n", *kdbxOut)
+		} else {
+			fmt.Fprintf(os.Stderr, "Wrote %d entries to %s.\n", len(passwords), *kdbxOut)
+		}
+		if entropyLine != "" {
+			fmt.Fprintln(os.Stderr, entropyLine)
+		}
+		return
+	}
+
+	if vault {
+		if err := vaultFormats[*format](os.Stdout, accounts, passwords); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		if entropyLine != "" {
+			fmt.Fprintln(os.Stderr, entropyLine)
+		}
+		return
+	}
+
+	var hashes []string
+	if hashCfg.Scheme != "" {
+		for _, p := range passwords {
+			h, err := hashPassword(p, hashCfg)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+				os.Exit(1)
+			}
+			hashes = append(hashes, h)
+		}
+	}
+	if err := printPasswords(passwords, hashes, hashCfg.Scheme, *user, *format); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	if derive && entropyLine != "" {
+		entropyLine += ", but no stronger than the master password"
+	}
+	if entropyLine != "" {
+		fmt.Fprintln(os.Stderr, entropyLine)
 	}
 
 	if !*noCopy && len(passwords) > 0 {
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// TestMain lets tests run passgen itself: runPassgen re-executes the test
+// binary with PASSGEN_TEST_MAIN set, which runs main instead of the tests.
+func TestMain(m *testing.M) {
+	if os.Getenv("PASSGEN_TEST_MAIN") == "1" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runPassgen runs passgen with args, feeding it stdin, and returns what it
+// wrote to stdout and stderr.
+func runPassgen(t *testing.T, stdin string, args ...string) (stdout, stderr string, err error) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(os.Environ(), "PASSGEN_TEST_MAIN=1")
+	cmd.Stdin = strings.NewReader(stdin)
+	var out, errOut strings.Builder
+	cmd.Stdout, cmd.Stderr = &out, &errOut
+	err = cmd.Run()
+	return out.String(), errOut.String(), err
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// ── Markov pseudo-words ──────────────────────────────────────────────────────
+//
+// An order-N character model: each letter is drawn given the N before it,
+// with the odds seen in the training words. Every draw's probability is
+// known, so each password's entropy is computed exactly rather than estimated.
+
+const (
+	markovEdge   = '\x00' // pads the start of a word and marks its end
+	markovMinLen = 4      // shorter samples are redrawn
+	markovMaxLen = 12     // longer samples are redrawn
+
+	defaultMarkovEntropy = 60 // bits per password when -min-entropy isn't set
+)
+
+// markovNext holds the letters seen after one state, with cumulative counts.
+type markovNext struct {
+	runes []rune
+	cum   []int
+}
+
+type markovModel struct {
+	order  int
+	states map[string]*markovNext // keyed by the previous order runes
+	known  map[string]bool        // training words, redrawn if sampled
+	accept float64                // chance a raw sample is kept
+}
+
+// markovCorpus returns the embedded word lists, the default training set.
+func markovCorpus() []string {
+	var corpus []string
+	for _, list := range [][]string{wordList, adjectives, nouns, verbs, adverbs} {
+		corpus = append(corpus, list...)
+	}
+	return uniqueStrings(corpus)
+}
+
+// readCorpus loads training words from a text file: every run of letters,
+// lowercased, that is at least two letters long.
+func readCorpus(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("-corpus: %v", err)
+	}
+	fields := strings.FieldsFunc(nfc(strings.ToLower(string(data))), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.Is(unicode.Mn, r)
+	})
+	var words []string
+	for _, w := range fields {
+		if len([]rune(w)) >= 2 {
+			words = append(words, w)
+		}
+	}
+	if len(words) == 0 {
+		return nil, fmt.Errorf("-corpus: no words in %s", path)
+	}
+	return uniqueStrings(words), nil
+}
+
+func trainMarkov(corpus []string, order int) (*markovModel, error) {
+	if order < 1 {
+		return nil, fmt.Errorf("-order must be >= 1")
+	}
+	counts := make(map[string]map[rune]int)
+	m := &markovModel{order: order, states: make(map[string]*markovNext), known: make(map[string]bool)}
+	for _, w := range corpus {
+		w = strings.ToLower(w)
+		m.known[w] = true
+		padded := []rune(strings.Repeat(string(markovEdge), order) + w + string(markovEdge))
+		for i := order; i < len(padded); i++ {
+			state := string(padded[i-order : i])
+			if counts[state] == nil {
+				counts[state] = make(map[rune]int)
+			}
+			counts[state][padded[i]]++
+		}
+	}
+	for state, next := range counts {
+		n := &markovNext{}
+		for r := range next {
+			n.runes = append(n.runes, r)
+		}
+		sort.Slice(n.runes, func(i, j int) bool { return n.runes[i] < n.runes[j] })
+		total := 0
+		for _, r := range n.runes {
+			total += next[r]
+			n.cum = append(n.cum, total)
+		}
+		m.states[state] = n
+	}
+
+	// A sample is kept when its length is in range and it isn't a training
+	// word; conditioning on that scales every kept word's probability by
+	// 1/accept.
+	m.accept = m.lengthOdds() - m.knownOdds()
+	if m.accept < 1e-6 {
+		return nil, fmt.Errorf("the corpus is too small for an order-%d model to make up new %d–%d letter words — lower -order or use a bigger -corpus", order, markovMinLen, markovMaxLen)
+	}
+	return m, nil
+}
+
+// prob returns the chance of drawing r in state, 0 if never seen.
+func (m *markovModel) prob(state string, r rune) float64 {
+	n := m.states[state]
+	if n == nil {
+		return 0
+	}
+	i := sort.Search(len(n.runes), func(i int) bool { return n.runes[i] >= r })
+	if i == len(n.runes) || n.runes[i] != r {
+		return 0
+	}
+	c := n.cum[i]
+	if i > 0 {
+		c -= n.cum[i-1]
+	}
+	return float64(c) / float64(n.cum[len(n.cum)-1])
+}
+
+// lengthOdds returns the chance a raw sample is markovMinLen–markovMaxLen
+// letters long, by pushing the state distribution forward one letter at a time.
+func (m *markovModel) lengthOdds() float64 {
+	start := strings.Repeat(string(markovEdge), m.order)
+	dist := map[string]float64{start: 1}
+	odds := 0.0
+	for n := 0; n <= markovMaxLen && len(dist) > 0; n++ {
+		next := make(map[string]float64)
+		for state, p := range dist {
+			for _, r := range m.states[state].runes {
+				q := p * m.prob(state, r)
+				if r == markovEdge {
+					if n >= markovMinLen {
+						odds += q
+					}
+					continue
+				}
+				next[string(append([]rune(state)[1:], r))] += q
+			}
+		}
+		dist = next
+	}
+	return odds
+}
+
+// knownOdds returns the chance a raw sample is a training word of a
+// length that would otherwise be kept.
+func (m *markovModel) knownOdds() float64 {
+	odds := 0.0
+	for w := range m.known {
+		if n := len([]rune(w)); n >= markovMinLen && n <= markovMaxLen {
+			odds += math.Exp2(-m.surprisal(w))
+		}
+	}
+	return odds
+}
+
+// surprisal returns -log2 of the chance the model draws exactly w.
+func (m *markovModel) surprisal(w string) float64 {
+	state := []rune(strings.Repeat(string(markovEdge), m.order))
+	bits := 0.0
+	for _, r := range []rune(w + string(markovEdge)) {
+		bits -= math.Log2(m.prob(string(state), r))
+		state = append(state[1:], r)
+	}
+	return bits
+}
+
+// word draws one pseudo-word and returns it with its entropy in bits: the
+// surprisal of every letter drawn, less what redrawing rejects takes away.
+func (m *markovModel) word() (string, float64, error) {
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		state := []rune(strings.Repeat(string(markovEdge), m.order))
+		var w []rune
+		bits := 0.0
+		for len(w) <= markovMaxLen {
+			n := m.states[string(state)]
+			x, err := randInt(n.cum[len(n.cum)-1])
+			if err != nil {
+				return "", 0, err
+			}
+			i := sort.SearchInts(n.cum, x+1)
+			r := n.runes[i]
+			bits -= math.Log2(m.prob(string(state), r))
+			if r == markovEdge {
+				break
+			}
+			w = append(w, r)
+			state = append(state[1:], r)
+		}
+		s := string(w)
+		if len(w) >= markovMinLen && len(w) <= markovMaxLen && !m.known[s] {
+			return s, bits + math.Log2(m.accept), nil
+		}
+	}
+	return "", 0, fmt.Errorf("no new word after %d attempts", maxAttempts)
+}
+
+// generateMarkov adds pseudo-words until the password carries at least
+// cfg.MinEntropy bits, and returns it with its exact entropy.
+func generateMarkov(m *markovModel, cfg MarkovConfig) (string, float64, error) {
+	var words []string
+	wordBits := 0.0
+	for {
+		w, bits, err := m.word()
+		if err != nil {
+			return "", 0, err
+		}
+		words = append(words, w)
+		wordBits += bits + transformEntropy(cfg.PassphraseConfig, w)
+		if total := wordBits + phraseExtras(cfg.PassphraseConfig, len(words)); total >= cfg.MinEntropy {
+			p, err := finishPhrase(cfg.PassphraseConfig, words)
+			return p, total, err
+		}
+	}
+}
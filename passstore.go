@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ── password-store (pass) ────────────────────────────────────────────────────
+//
+// passgen store pass writes a password the way `pass insert` does: a .gpg
+// file under $PASSWORD_STORE_DIR, encrypted with the local gpg for the
+// recipients in the nearest .gpg-id, password on the first line and any
+// metadata after it.
+
+// passStoreDir returns the store root, $PASSWORD_STORE_DIR or ~/.password-store.
+func passStoreDir() (string, error) {
+	if dir := os.Getenv("PASSWORD_STORE_DIR"); dir != "" {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".password-store"), nil
+}
+
+// passEntryFile checks name, like work/db/prod, and returns its file in the store.
+func passEntryFile(store, name string) (string, error) {
+	clean := filepath.ToSlash(filepath.Clean(name))
+	if name == "" || strings.HasSuffix(name, "/") || filepath.IsAbs(name) || clean == "." || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("-path must name an entry inside the store, like work/db/prod — not %q", name)
+	}
+	return filepath.Join(store, filepath.FromSlash(clean)+".gpg"), nil
+}
+
+// gpgRecipients reads the .gpg-id nearest to file, looking up from its
+// directory to the store root as pass does.
+func gpgRecipients(store, file string) ([]string, error) {
+	for dir := filepath.Dir(file); ; dir = filepath.Dir(dir) {
+		data, err := os.ReadFile(filepath.Join(dir, ".gpg-id"))
+		if err == nil {
+			var ids []string
+			scanner := bufio.NewScanner(bytes.NewReader(data))
+			for scanner.Scan() {
+				line, _, _ := strings.Cut(scanner.Text(), "#")
+				if line = strings.TrimSpace(line); line != "" {
+					ids = append(ids, line)
+				}
+			}
+			if len(ids) == 0 {
+				return nil, fmt.Errorf("%s lists no recipients", filepath.Join(dir, ".gpg-id"))
+			}
+			return ids, nil
+		}
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+		if rel, _ := filepath.Rel(store, dir); rel == "." || dir == filepath.Dir(dir) {
+			return nil, fmt.Errorf("no .gpg-id in %s — run `pass init` first", store)
+		}
+	}
+}
+
+// gpgCommand returns the gpg binary pass would use.
+func gpgCommand() (string, error) {
+	for _, name := range []string{"gpg2", "gpg"} {
+		if commandExists(name) {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("gpg not found — install GnuPG to write to the password store")
+}
+
+// checkPassEntry returns the entry's file, refusing to replace one
+// without force. It runs before anything is generated.
+func checkPassEntry(name string, force bool) (string, error) {
+	store, err := passStoreDir()
+	if err != nil {
+		return "", err
+	}
+	file, err := passEntryFile(store, name)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(file); err == nil && !force {
+		return "", fmt.Errorf("%s is already in the password store — use -force to replace it", name)
+	}
+	return file, nil
+}
+
+// storePass encrypts content to the entry's file, committing it when the
+// store is a git repository.
+func storePass(name, file, content string) error {
+	store, err := passStoreDir()
+	if err != nil {
+		return err
+	}
+	recipients, err := gpgRecipients(store, file)
+	if err != nil {
+		return err
+	}
+	gpg, err := gpgCommand()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o700); err != nil {
+		return err
+	}
+
+	// Encrypt to a temporary file so a failure never leaves half an entry
+	tmp := file + ".tmp"
+	args := []string{"--encrypt", "--quiet", "--yes", "--batch", "--compress-algo=none", "--no-encrypt-to", "--output", tmp}
+	args = append(args, strings.Fields(os.Getenv("PASSWORD_STORE_GPG_OPTS"))...)
+	for _, r := range recipients {
+		args = append(args, "--recipient", r)
+	}
+	cmd := exec.Command(gpg, args...)
+	cmd.Stdin = strings.NewReader(content)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("%s: %v: %s", gpg, err, strings.TrimSpace(stderr.String()))
+	}
+	if err := os.Chmod(tmp, 0o600); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, file); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	if _, err := os.Stat(filepath.Join(store, ".git")); err == nil {
+		git := func(args ...string) error {
+			return exec.Command("git", append([]string{"-C", store}, args...)...).Run()
+		}
+		err := git("add", "--", file)
+		if err == nil {
+			err = git("commit", "-q", "-m", "Add generated password for "+name+".")
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "(git commit in the password store failed: %v)\n", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPassEntryFile(t *testing.T) {
+	store := filepath.Join("home", ".password-store")
+	for name, want := range map[string]string{
+		"github":       "github.gpg",
+		"work/db/prod": filepath.Join("work", "db", "prod.gpg"),
+		"work//db/":    "",
+		"a/../b":       "b.gpg",
+		"./email":      "email.gpg",
+		"":             "",
+		"/etc/passwd":  "",
+		"..":           "",
+		"../outside":   "",
+		"a/../../b":    "",
+	} {
+		file, err := passEntryFile(store, name)
+		switch {
+		case want == "" && err == nil:
+			t.Errorf("passEntryFile(%q) = %s, want an error", name, file)
+		case want != "" && err != nil:
+			t.Errorf("passEntryFile(%q): %v", name, err)
+		case want != "" && file != filepath.Join(store, want):
+			t.Errorf("passEntryFile(%q) = %s, want %s", name, file, filepath.Join(store, want))
+		}
+	}
+}
+
+// fakeGPG puts a gpg on an otherwise empty PATH that writes what it's
+// given to --output and records its arguments, one per line, in the
+// returned file.
+func fakeGPG(t *testing.T) (argsFile string) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the gpg stub is a shell script")
+	}
+	bin := t.TempDir()
+	argsFile = filepath.Join(t.TempDir(), "args")
+	script := `#!/bin/sh
+printf '%s\n' "$@" > "$GPG_STUB_ARGS"
+while [ $# -gt 0 ]; do
+	[ "$1" = --output ] && out=$2
+	shift
+done
+while IFS= read -r line; do printf '%s\n' "$line"; done > "$out"
+`
+	if err := os.WriteFile(filepath.Join(bin, "gpg"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin)
+	t.Setenv("GPG_STUB_ARGS", argsFile)
+	return argsFile
+}
+
+// passStore makes an empty store with .gpg-id files at the given paths.
+func passStore(t *testing.T, gpgIDs map[string]string) string {
+	store := t.TempDir()
+	t.Setenv("PASSWORD_STORE_DIR", store)
+	for dir, ids := range gpgIDs {
+		if err := os.MkdirAll(filepath.Join(store, dir), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(store, dir, ".gpg-id"), []byte(ids), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return store
+}
+
+func TestStorePass(t *testing.T) {
+	argsFile := fakeGPG(t)
+	store := passStore(t, map[string]string{
+		".":    "root@example.com\n",
+		"work": "# the ops team\nops@example.com\nbackup@example.com # offline key\n",
+	})
+
+	file, err := checkPassEntry("work/db/prod", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := "s3cret\nlogin: admin\n"
+	if err := storePass("work/db/prod", file, content); err != nil {
+		t.Fatal(err)
+	}
+
+	// The entry sits where pass looks for it, readable by its owner only
+	want := filepath.Join(store, "work", "db", "prod.gpg")
+	if file != want {
+		t.Errorf("entry file %s, want %s", file, want)
+	}
+	info, err := os.Stat(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0o600 {
+		t.Errorf("entry mode %o, want 600", mode)
+	}
+	if data, _ := os.ReadFile(want); string(data) != content {
+		t.Errorf("gpg was given %q, want %q", data, content)
+	}
+	if _, err := os.Stat(want + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary file left behind")
+	}
+
+	// Encrypted to the nearest .gpg-id, work/.gpg-id, and not the root's
+	data, _ := os.ReadFile(argsFile)
+	args := strings.Split(strings.TrimSpace(string(data)), "\n")
+	var recipients []string
+	for i, a := range args {
+		if a == "--recipient" {
+			recipients = append(recipients, args[i+1])
+		}
+	}
+	if !slices.Equal(recipients, []string{"ops@example.com", "backup@example.com"}) {
+		t.Errorf("recipients %q, want those in work/.gpg-id", recipients)
+	}
+
+	if _, err := checkPassEntry("work/db/prod", false); err == nil || !strings.Contains(err.Error(), "already in the password store") {
+		t.Errorf("replacing an entry without -force: %v", err)
+	}
+	if _, err := checkPassEntry("work/db/prod", true); err != nil {
+		t.Errorf("replacing an entry with -force: %v", err)
+	}
+}
+
+func TestStorePassNoGPGID(t *testing.T) {
+	fakeGPG(t)
+	store := passStore(t, nil)
+	file, err := checkPassEntry("github", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = storePass("github", file, "s3cret\n")
+	if err == nil || !strings.Contains(err.Error(), "no .gpg-id in "+store) {
+		t.Errorf("storing without a .gpg-id: %v", err)
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Errorf("an entry was written without a .gpg-id")
+	}
+}
+
+func TestStorePassNoGPG(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	passStore(t, map[string]string{".": "root@example.com\n"})
+	file, err := checkPassEntry("github", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = storePass("github", file, "s3cret\n")
+	if err == nil || !strings.Contains(err.Error(), "gpg not found") {
+		t.Errorf("storing without gpg: %v", err)
+	}
+}
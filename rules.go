@@ -0,0 +1,265 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+)
+
+// ── Password rules (Apple "passwordrules" attribute syntax) ──────────────────
+//
+// e.g. minlength: 12; required: lower; required: upper; allowed: [-().&@?'#,/"+]; max-consecutive: 2
+//
+// Each `required` rule names one or more classes of which at least one
+// character must appear; `allowed` widens the pool without a guarantee.
+
+const (
+	// Apple's "special" class, minus the space character: a space is legal
+	// in the grammar but a terrible thing to hand out in a password.
+	ruleSpecial        = "-~!@#$%^&*_+=`|(){}[:;\"'<>,.?]"
+	ruleASCIIPrintable = charUppercase + charLowercase + charDigits + ruleSpecial
+)
+
+type PasswordRules struct {
+	Required       []string // one entry per `required` rule; each must contribute a character
+	Allowed        string   // union of all `allowed` rules
+	MinLength      int
+	MaxLength      int // 0 = no upper bound
+	MaxConsecutive int // 0 = unlimited
+}
+
+func parsePasswordRules(input string) (PasswordRules, error) {
+	var r PasswordRules
+	// Rules are usually copied straight out of an HTML attribute.
+	s := html.UnescapeString(input)
+	pos := 0
+
+	skipSpace := func() {
+		for pos < len(s) && (s[pos] == ' ' || s[pos] == '\t' || s[pos] == '\n' || s[pos] == '\r') {
+			pos++
+		}
+	}
+
+	for {
+		skipSpace()
+		if pos >= len(s) {
+			break
+		}
+		if s[pos] == ';' {
+			pos++
+			continue
+		}
+
+		// Property name
+		start := pos
+		for pos < len(s) && (s[pos] == '-' || (s[pos] >= 'a' && s[pos] <= 'z') || (s[pos] >= 'A' && s[pos] <= 'Z')) {
+			pos++
+		}
+		name := strings.ToLower(s[start:pos])
+		if name == "" {
+			return r, fmt.Errorf("rules: expected a property name at offset %d", pos)
+		}
+		skipSpace()
+		if pos >= len(s) || s[pos] != ':' {
+			return r, fmt.Errorf("rules: expected ':' after %q", name)
+		}
+		pos++
+
+		// Value runs to the next ';' outside a custom [...] class
+		start = pos
+		for pos < len(s) && s[pos] != ';' {
+			if s[pos] == '[' {
+				end, err := customClassEnd(s, pos)
+				if err != nil {
+					return r, err
+				}
+				pos = end
+				continue
+			}
+			pos++
+		}
+		value := strings.TrimSpace(s[start:pos])
+
+		switch name {
+		case "required":
+			set, err := parseRuleClasses(value)
+			if err != nil {
+				return r, fmt.Errorf("rules: required: %v", err)
+			}
+			r.Required = append(r.Required, set)
+		case "allowed":
+			set, err := parseRuleClasses(value)
+			if err != nil {
+				return r, fmt.Errorf("rules: allowed: %v", err)
+			}
+			r.Allowed = mergeChars(r.Allowed, set)
+		case "minlength", "maxlength", "max-consecutive":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return r, fmt.Errorf("rules: %s: %q is not a non-negative integer", name, value)
+			}
+			switch name {
+			case "minlength":
+				if n > r.MinLength {
+					r.MinLength = n
+				}
+			case "maxlength":
+				if r.MaxLength == 0 || n < r.MaxLength {
+					r.MaxLength = n
+				}
+			case "max-consecutive":
+				if r.MaxConsecutive == 0 || n < r.MaxConsecutive {
+					r.MaxConsecutive = n
+				}
+			}
+		default:
+			return r, fmt.Errorf("rules: unknown property %q", name)
+		}
+	}
+
+	// With no character rules at all, anything printable goes.
+	if len(r.Required) == 0 && r.Allowed == "" {
+		r.Allowed = ruleASCIIPrintable
+	}
+	return r, nil
+}
+
+// customClassEnd returns the offset just past the ']' closing the custom
+// class that opens at s[open]. A ']' straight after the '[' is taken
+// literally, as is the first of a closing "]]", so [-], []] and [-]] all
+// parse.
+func customClassEnd(s string, open int) (int, error) {
+	i := open + 1
+	if i < len(s) && s[i] == ']' {
+		i++
+	}
+	for i < len(s) {
+		if s[i] == ']' {
+			if i+1 < len(s) && s[i+1] == ']' {
+				i++
+			}
+			return i + 1, nil
+		}
+		i++
+	}
+	return 0, fmt.Errorf("rules: unterminated character class at offset %d", open)
+}
+
+func parseRuleClasses(value string) (string, error) {
+	var set string
+	pos := 0
+	for pos < len(value) {
+		switch c := value[pos]; {
+		case c == ' ' || c == '\t' || c == ',':
+			pos++
+		case c == '[':
+			end, err := customClassEnd(value, pos)
+			if err != nil {
+				return "", err
+			}
+			chars := value[pos+1 : end-1]
+			if strings.LastIndex(chars, "-") > 0 {
+				return "", fmt.Errorf("'-' must be the first character of a custom class")
+			}
+			for _, ch := range chars {
+				if ch < 0x20 || ch > 0x7e {
+					return "", fmt.Errorf("custom class %q contains a non-ASCII-printable character", "["+chars+"]")
+				}
+			}
+			if chars == "" {
+				return "", fmt.Errorf("empty custom class []")
+			}
+			set = mergeChars(set, chars)
+			pos = end
+		default:
+			start := pos
+			for pos < len(value) && value[pos] != ',' && value[pos] != ' ' && value[pos] != '[' {
+				pos++
+			}
+			name := strings.ToLower(value[start:pos])
+			switch name {
+			case "upper":
+				set = mergeChars(set, charUppercase)
+			case "lower":
+				set = mergeChars(set, charLowercase)
+			case "digit":
+				set = mergeChars(set, charDigits)
+			case "special":
+				set = mergeChars(set, ruleSpecial)
+			case "ascii-printable", "unicode":
+				// "unicode" permits anything; printable ASCII always complies.
+				set = mergeChars(set, ruleASCIIPrintable)
+			default:
+				return "", fmt.Errorf("unknown character class %q", name)
+			}
+		}
+	}
+	if set == "" {
+		return "", fmt.Errorf("no character classes given")
+	}
+	return set, nil
+}
+
+// mergeChars returns the union of a and b in printable-ASCII order.
+func mergeChars(a, b string) string {
+	var sb strings.Builder
+	for ch := rune(0x20); ch <= 0x7e; ch++ {
+		if strings.ContainsRune(a, ch) || strings.ContainsRune(b, ch) {
+			sb.WriteRune(ch)
+		}
+	}
+	return sb.String()
+}
+
+// clampLength pulls a default length into the rules' [minlength, maxlength] range.
+func (r PasswordRules) clampLength(n int) int {
+	if n < r.MinLength {
+		n = r.MinLength
+	}
+	if r.MaxLength > 0 && n > r.MaxLength {
+		n = r.MaxLength
+	}
+	return n
+}
+
+// randomConfig translates the rules into a RandomConfig for generateRandom,
+// rejecting any combination that no password could satisfy.
+func (r PasswordRules) randomConfig(length int, exclude string) (RandomConfig, error) {
+	if r.MaxLength > 0 && r.MinLength > r.MaxLength {
+		return RandomConfig{}, fmt.Errorf("rules are unsatisfiable: minlength %d > maxlength %d", r.MinLength, r.MaxLength)
+	}
+	if length < r.MinLength || (r.MaxLength > 0 && length > r.MaxLength) {
+		if r.MaxLength > 0 {
+			return RandomConfig{}, fmt.Errorf("-length %d is outside the rules' range %d–%d", length, r.MinLength, r.MaxLength)
+		}
+		return RandomConfig{}, fmt.Errorf("-length %d is below the rules' minlength %d", length, r.MinLength)
+	}
+
+	var required []string
+	charset := filterChars(r.Allowed, exclude)
+	for _, set := range r.Required {
+		filtered := filterChars(set, exclude)
+		if filtered == "" {
+			return RandomConfig{}, fmt.Errorf("rules are unsatisfiable: every character of required class %q is excluded", set)
+		}
+		required = append(required, filtered)
+		charset = mergeChars(charset, filtered)
+	}
+	if charset == "" {
+		return RandomConfig{}, fmt.Errorf("rules are unsatisfiable: no characters left after -exclude")
+	}
+	if len(required) > length {
+		return RandomConfig{}, fmt.Errorf("rules are unsatisfiable: %d required classes do not fit in %d characters", len(required), length)
+	}
+	if r.MaxConsecutive > 0 && len(charset) == 1 && length > r.MaxConsecutive {
+		return RandomConfig{}, fmt.Errorf("rules are unsatisfiable: only %q is available but max-consecutive is %d", charset, r.MaxConsecutive)
+	}
+
+	return RandomConfig{
+		Length:      length,
+		Charset:     charset,
+		Required:    required,
+		Constraints: Constraints{MaxConsecutive: r.MaxConsecutive},
+	}, nil
+}
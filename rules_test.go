@@ -0,0 +1,53 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParsePasswordRules(t *testing.T) {
+	for _, tt := range []struct {
+		rules    string
+		required []string
+		allowed  string
+	}{
+		{"required: upper; required: digit", []string{charUppercase, charDigits}, ""},
+		{"required: lower, upper; allowed: [-_]", []string{charUppercase + charLowercase}, "-_"},
+		{"required: [-]", []string{"-"}, ""},
+		{"allowed: [-]", nil, "-"},
+		{"required: []]", []string{"]"}, ""},
+		{"required: [-]]; required: digit", []string{"-]", charDigits}, ""},
+		{"allowed: [abc]]", nil, "]abc"},
+		{"allowed: []abc]", nil, "]abc"},
+		{"required: [-().&@?'#,/&quot;+]; minlength: 12", []string{"\"#&'()+,-./?@"}, ""},
+		{"allowed: [;]; required: [:]", []string{":"}, ";"},
+	} {
+		r, err := parsePasswordRules(tt.rules)
+		if err != nil {
+			t.Errorf("parsePasswordRules(%q): %v", tt.rules, err)
+			continue
+		}
+		if strings.Join(r.Required, " ") != strings.Join(tt.required, " ") || r.Allowed != tt.allowed {
+			t.Errorf("parsePasswordRules(%q) = required %q, allowed %q; want %q, %q", tt.rules, r.Required, r.Allowed, tt.required, tt.allowed)
+		}
+	}
+}
+
+func TestParsePasswordRulesErrors(t *testing.T) {
+	for rules, want := range map[string]string{
+		"required: [abc":    "unterminated character class",
+		"required: []":      "unterminated character class",
+		"required: [a-z]":   "'-' must be the first character",
+		"allowed: [é]":      "non-ASCII-printable",
+		"required: vowels":  "unknown character class",
+		"minlength: -1":     "not a non-negative integer",
+		"colour: blue":      "unknown property",
+		"required upper":    "expected ':'",
+		"allowed: [-]; : x": "expected a property name",
+	} {
+		_, err := parsePasswordRules(rules)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("parsePasswordRules(%q): %v, want %q", rules, err, want)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ── Sentence templates ───────────────────────────────────────────────────────
+//
+// A template is a list of part-of-speech slots, each filled with a random
+// word from its list: "adjective noun verb adverb" gives Brave-Otter-Juggles-Quietly.
+
+const defaultTemplate = "adjective noun verb adverb"
+
+var sentenceSlots = map[string][]string{
+	"adjective": adjectives,
+	"noun":      nouns,
+	"verb":      verbs,
+	"adverb":    adverbs,
+}
+
+var slotAliases = map[string]string{"adj": "adjective", "adv": "adverb"}
+
+// parseTemplate parses a comma/space separated list of slot names.
+func parseTemplate(raw string) ([]string, error) {
+	var slots []string
+	for _, name := range splitWords(strings.ToLower(raw)) {
+		if full, ok := slotAliases[name]; ok {
+			name = full
+		}
+		if _, ok := sentenceSlots[name]; !ok {
+			return nil, fmt.Errorf("-template: unknown slot %q — use adjective, noun, verb or adverb", name)
+		}
+		slots = append(slots, name)
+	}
+	if len(slots) == 0 {
+		return nil, fmt.Errorf("-template needs at least one slot")
+	}
+	return slots, nil
+}
+
+// templateLists returns the word list behind each slot of the template.
+func templateLists(template []string) [][]string {
+	lists := make([][]string, len(template))
+	for i, slot := range template {
+		lists[i] = sentenceSlots[slot]
+	}
+	return lists
+}
+
+func generateSentence(cfg SentenceConfig) (string, error) {
+	lists := templateLists(cfg.Template)
+	return drawFitting(cfg.MaxLength, func() (string, error) {
+		words := make([]string, len(lists))
+		for i, list := range lists {
+			idx, err := randInt(len(list))
+			if err != nil {
+				return "", err
+			}
+			words[i] = list[idx]
+		}
+		return finishPhrase(cfg.PassphraseConfig, words)
+	})
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSentenceTemplateLength(t *testing.T) {
+	// One noun and a number before or after it, not the two of four words'
+	// five places
+	out, errOut, err := runPassgen(t, "", "-no-copy", "-type", "sentence", "-template", "noun", "-number-anywhere")
+	if err != nil {
+		t.Fatalf("passgen: %v\n%s", err, errOut)
+	}
+	if parts := strings.Split(strings.TrimSpace(out), "-"); len(parts) != 2 {
+		t.Errorf("one-noun sentence %q has %d parts, want a word and a number", out, len(parts))
+	}
+	if want := "Entropy: ~19.0 bits\n"; errOut != want {
+		t.Errorf("passgen said %q, want %q", errOut, want)
+	}
+}
+
+func TestSentenceMaxLength(t *testing.T) {
+	template := "adjective noun noun noun noun noun noun noun noun"
+	out, errOut, err := runPassgen(t, "", "-no-copy", "-type", "sentence", "-template", template, "-max-length", "60")
+	if err != nil {
+		t.Fatalf("passgen: %v\n%s", err, errOut)
+	}
+	out = strings.TrimSpace(out)
+	if n := utf8.RuneCountInString(out); n > 60 || strings.Count(out, "-") != 9 {
+		t.Errorf("%q: want 9 words and a number in at most 60 characters", out)
+	}
+	if !strings.Contains(errOut, "max-length") {
+		t.Errorf("entropy line %q doesn't count the -max-length cost", errOut)
+	}
+
+	// A cap almost nothing fits in is refused, not tried 2^20 times
+	_, errOut, err = runPassgen(t, "", "-no-copy", "-type", "sentence", "-template", template, "-max-length", "40")
+	if err == nil || !strings.Contains(errOut, "fits in -max-length 40") {
+		t.Errorf("9 words in 40 characters: %v\n%s", err, errOut)
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+// ── Sentence word lists (tagged by part of speech for -type sentence) ───────
+
+// Adjectives, e.g. brave.
+var adjectives = []string{
+	"able", "active", "agile", "alert", "amber", "ample", "ancient", "angry",
+	"arctic", "artful", "autumn", "awake", "bashful", "bold", "bouncy", "brave",
+	"breezy", "bright", "brisk", "broad", "bronze", "bubbly", "bumpy", "busy",
+	"calm", "candid", "careful", "cheerful", "chilly", "chubby", "civic",
+	"classic", "clean", "clever", "cloudy", "clumsy", "coastal", "cosmic",
+	"cozy", "crafty", "crisp", "crunchy", "cuddly", "curious", "curly", "dapper",
+	"daring", "dazzling", "eager", "early", "earnest", "easy", "electric",
+	"elegant", "epic", "fancy", "fearless", "festive", "fierce", "fluffy",
+	"fond", "frank", "friendly", "frosty", "funny", "fuzzy", "gallant", "gentle",
+	"giant", "giddy", "gifted", "glad", "gleaming", "glossy", "golden",
+	"graceful", "grand", "grateful", "groovy", "grumpy", "handy", "happy",
+	"hardy", "hasty", "hearty", "helpful", "heroic", "hidden", "hollow",
+	"honest", "hopeful", "humble", "hungry", "icy", "idle", "jazzy", "jolly",
+	"jovial", "joyful", "jumbo", "keen", "kind", "lanky", "lavish", "lazy",
+	"legal", "lively", "lofty", "loud", "lovely", "loyal", "lucky", "lumpy",
+	"magic", "majestic", "marble", "mellow", "merry", "mighty", "misty",
+	"modern", "modest", "moody", "muddy", "musical", "mystic", "narrow", "neat",
+	"nervous", "nimble", "noble", "noisy", "odd", "olive", "orange", "orderly",
+	"patient", "peaceful", "peppy", "perky", "plucky", "plump", "polite",
+	"proud", "purple", "quick", "quiet", "quirky", "rapid", "rare", "ready",
+	"regal", "rich", "robust", "rosy", "rowdy", "royal", "rugged", "rusty",
+	"salty", "sandy", "scarlet", "secret", "shaggy", "sharp", "shiny", "shy",
+	"silent", "silky", "silly", "silver", "simple", "sleepy", "slim", "smart",
+	"smooth", "snappy", "snowy", "soft", "solar", "solid", "speedy", "spicy",
+	"spiffy", "spotted", "steady", "stormy", "sturdy", "sunny", "super", "swift",
+	"tall", "tame", "tender", "thrifty", "tidy", "timid", "tiny", "tough",
+	"tricky", "trusty", "twin", "upbeat", "urban", "valiant", "velvet", "vivid",
+	"wacky", "warm", "wavy", "wealthy", "weary", "wild", "windy", "wise",
+	"witty", "wooden", "worthy", "young", "zany", "zesty", "zippy",
+}
+
+// Singular nouns — mostly animals and everyday things, e.g. otter.
+var nouns = []string{
+	"acorn", "alpaca", "anchor", "antler", "apple", "apron", "arrow", "atlas",
+	"avocado", "badger", "bagel", "ballad", "balloon", "bamboo", "banana",
+	"banjo", "barrel", "basket", "beacon", "beaver", "beetle", "berry",
+	"bicycle", "biscuit", "bison", "blanket", "blender", "blossom", "bobcat",
+	"bonnet", "bottle", "boulder", "bracelet", "bramble", "bucket", "buffalo",
+	"bugle", "bunny", "burrito", "butter", "button", "cabin", "cactus", "camel",
+	"camera", "candle", "canoe", "canyon", "captain", "caramel", "carrot",
+	"castle", "catfish", "cello", "cheetah", "cherry", "chimney", "chipmunk",
+	"cobra", "comet", "compass", "cookie", "cougar", "coyote", "crab", "crayon",
+	"cricket", "crocodile", "crystal", "cupcake", "dagger", "daisy", "dolphin",
+	"donkey", "dragon", "drum", "duckling", "eagle", "easel", "eclipse",
+	"elephant", "elk", "falcon", "feather", "ferret", "fiddle", "flamingo",
+	"flute", "fossil", "fox", "gazelle", "gecko", "geyser", "giraffe", "glacier",
+	"goblin", "goose", "gopher", "gorilla", "guitar", "hamster", "harbor",
+	"harp", "hawk", "hedgehog", "helmet", "heron", "hippo", "honey", "hornet",
+	"iceberg", "igloo", "iguana", "island", "jackal", "jaguar", "jellyfish",
+	"jester", "kangaroo", "kayak", "kettle", "kitten", "koala", "ladder",
+	"ladybug", "lagoon", "lantern", "lemon", "lemur", "leopard", "lighthouse",
+	"lily", "lion", "lizard", "llama", "lobster", "locket", "lynx", "magnet",
+	"mango", "maple", "meadow", "meerkat", "melon", "mermaid", "meteor",
+	"mitten", "mole", "monkey", "moose", "moth", "muffin", "narwhal", "nectar",
+	"needle", "nugget", "oak", "oasis", "octopus", "olive", "orca", "ostrich",
+	"otter", "owl", "oyster", "paddle", "panda", "panther", "parrot", "peach",
+	"peacock", "pebble", "pelican", "penguin", "pepper", "piano", "pickle",
+	"pigeon", "pilot", "pine", "pirate", "pizza", "planet", "platypus", "pony",
+	"poodle", "possum", "potato", "pretzel", "pudding", "puffin", "pumpkin",
+	"puppy", "python", "quail", "quartz", "rabbit", "raccoon", "radish",
+	"rainbow", "raven", "reindeer", "rhino", "river", "robin", "rocket",
+	"rooster", "saddle", "salmon", "sandal", "satellite", "scarf", "scooter",
+	"seal", "shark", "sheep", "shovel", "skunk", "sloth", "snail", "sparrow",
+	"spider", "spoon", "squid", "squirrel", "starfish", "stork", "sunflower",
+	"swan", "taco", "teapot", "termite", "thistle", "tiger", "toad", "toaster",
+	"tomato", "tortoise", "toucan", "tractor", "trumpet", "tuba", "tulip",
+	"turkey", "turnip", "turtle", "unicorn", "urchin", "violin", "volcano",
+	"vulture", "waffle", "wagon", "walnut", "walrus", "weasel", "whale",
+	"whistle", "willow", "wizard", "wombat", "yak", "zebra",
+}
+
+// Verbs in the third person singular, e.g. juggles.
+var verbs = []string{
+	"admires", "argues", "bakes", "balances", "bargains", "bathes", "battles",
+	"beams", "bends", "blinks", "blooms", "boasts", "bounces", "bows",
+	"breathes", "builds", "bumps", "calculates", "camps", "carves", "catches",
+	"celebrates", "chases", "chats", "cheers", "chews", "chuckles", "climbs",
+	"collects", "cooks", "counts", "crawls", "cycles", "dances", "daydreams",
+	"dazzles", "delivers", "designs", "digs", "dives", "doodles", "drifts",
+	"drums", "dwells", "explores", "fetches", "fidgets", "fishes", "flips",
+	"floats", "flutters", "folds", "gallops", "giggles", "glides", "glows",
+	"gobbles", "grins", "grows", "guards", "hides", "hikes", "hops", "hovers",
+	"hugs", "hums", "hurries", "invents", "jogs", "jokes", "juggles", "jumps",
+	"kneels", "knits", "laughs", "launches", "leaps", "learns", "lingers",
+	"listens", "lounges", "marches", "meditates", "mingles", "mumbles",
+	"munches", "naps", "navigates", "nibbles", "nods", "orbits", "paddles",
+	"paints", "parades", "peeks", "performs", "pirouettes", "plays", "ponders",
+	"pounces", "practices", "prances", "preaches", "pretends", "prowls", "purrs",
+	"questions", "rambles", "reads", "relaxes", "rescues", "rests", "rides",
+	"roams", "rolls", "rows", "runs", "sails", "salutes", "scampers",
+	"scribbles", "searches", "shimmers", "shines", "shivers", "shuffles",
+	"sighs", "sings", "sips", "skates", "sketches", "skips", "sleeps", "slides",
+	"smiles", "sneezes", "snoozes", "soars", "sparkles", "splashes", "sprints",
+	"squeaks", "stomps", "strolls", "studies", "stumbles", "surfs", "swims",
+	"swings", "swirls", "teaches", "thinks", "tiptoes", "travels", "trots",
+	"tumbles", "twirls", "twists", "waddles", "waits", "wanders", "waves",
+	"whispers", "whistles", "wiggles", "winks", "wobbles", "wonders", "works",
+	"wrestles", "writes", "yawns", "yodels", "zigzags", "zooms",
+}
+
+// Adverbs of manner, e.g. quietly.
+var adverbs = []string{
+	"absently", "amiably", "anxiously", "awkwardly", "blissfully", "blithely",
+	"boldly", "bravely", "breezily", "briefly", "brightly", "briskly", "busily",
+	"calmly", "candidly", "carefully", "casually", "cautiously", "cheerfully",
+	"clearly", "cleverly", "closely", "coyly", "crisply", "curiously", "daily",
+	"deftly", "dreamily", "eagerly", "easily", "eerily", "elegantly", "evenly",
+	"fairly", "faithfully", "feverishly", "fiercely", "firmly", "fondly",
+	"frankly", "freely", "gallantly", "gamely", "gently", "gladly", "gleefully",
+	"gracefully", "gravely", "grimly", "happily", "hastily", "heartily",
+	"honestly", "hopefully", "humbly", "jauntily", "jovially", "joyfully",
+	"keenly", "kindly", "lavishly", "lazily", "lightly", "loudly", "lovingly",
+	"loyally", "madly", "meekly", "merrily", "mightily", "mildly", "neatly",
+	"nervously", "nicely", "nimbly", "nobly", "noisily", "oddly", "openly",
+	"patiently", "playfully", "politely", "promptly", "proudly", "quaintly",
+	"quickly", "quietly", "quirkily", "rapidly", "rarely", "readily", "safely",
+	"sagely", "serenely", "sharply", "shyly", "silently", "sleepily", "slowly",
+	"smoothly", "snugly", "softly", "solemnly", "speedily", "steadily",
+	"sternly", "sweetly", "swiftly", "tamely", "tenderly", "thankfully",
+	"tidily", "timidly", "truly", "vastly", "vividly", "warmly", "wearily",
+	"wildly", "wisely", "wittily", "yearly", "zealously", "zestfully",
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ── Shamir secret sharing over GF(256) ───────────────────────────────────────
+//
+// Each byte of the secret is the constant term of its own random polynomial
+// of degree threshold-1; share i holds every polynomial evaluated at x = i.
+// Any threshold shares recover the secret by Lagrange interpolation at 0.
+//
+// A share is self-describing text:
+//
+//	pgs1-<set>-<threshold>of<shares>-<index>-<payload hex>-<checksum>
+//
+// set is random per split so shares of different secrets can't be mixed,
+// and checksum (the first 4 bytes of SHA-256 over everything before it)
+// catches typos. The payload also carries the first 4 bytes of the
+// secret's SHA-256, so a wrong share that passes its own checksum is still
+// caught after reconstruction.
+
+const (
+	sharePrefix    = "pgs1"
+	shareCheckSize = 4
+)
+
+// GF(256) with the AES polynomial x⁸+x⁴+x³+x+1, via log/exp tables over
+// the generator 3.
+var gfExp, gfLog = func() (exp [510]byte, log [256]byte) {
+	x := byte(1)
+	for i := 0; i < 255; i++ {
+		exp[i], exp[i+255] = x, x
+		log[x] = byte(i)
+		// multiply by 3: x·2 reduced, then add x
+		hi := x & 0x80
+		x2 := x << 1
+		if hi != 0 {
+			x2 ^= 0x1b
+		}
+		x ^= x2
+	}
+	return
+}()
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+func gfDiv(a, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+255-int(gfLog[b])]
+}
+
+type share struct {
+	set       string
+	threshold int
+	shares    int
+	index     int
+	payload   []byte
+}
+
+func (s share) String() string {
+	body := fmt.Sprintf("%s-%s-%dof%d-%d-%s", sharePrefix, s.set, s.threshold, s.shares, s.index, hex.EncodeToString(s.payload))
+	sum := sha256.Sum256([]byte(body))
+	return body + "-" + hex.EncodeToString(sum[:shareCheckSize])
+}
+
+// splitSecret splits secret into n shares, any threshold of which recover it.
+func splitSecret(secret []byte, n, threshold int) ([]share, error) {
+	if threshold < 2 || n < threshold || n > 255 {
+		return nil, fmt.Errorf("need 2 <= -threshold <= -shares <= 255, got %d of %d", threshold, n)
+	}
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("empty secret")
+	}
+	set, err := randBytes(4)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256(secret)
+	data := append(append([]byte{}, secret...), digest[:shareCheckSize]...)
+
+	shares := make([]share, n)
+	for i := range shares {
+		shares[i] = share{hex.EncodeToString(set), threshold, n, i + 1, make([]byte, len(data))}
+	}
+	coeffs := make([]byte, threshold)
+	for b, secretByte := range data {
+		coeffs[0] = secretByte
+		random, err := randBytes(threshold - 1)
+		if err != nil {
+			return nil, err
+		}
+		copy(coeffs[1:], random)
+		for i := range shares {
+			// Horner's rule at x = index
+			x, y := byte(shares[i].index), byte(0)
+			for k := threshold - 1; k >= 0; k-- {
+				y = gfMul(y, x) ^ coeffs[k]
+			}
+			shares[i].payload[b] = y
+		}
+	}
+	return shares, nil
+}
+
+// parseShare decodes one share, checking its format and checksum.
+func parseShare(text string) (share, error) {
+	var s share
+	parts := strings.Split(strings.ToLower(strings.TrimSpace(text)), "-")
+	if len(parts) != 6 || parts[0] != sharePrefix {
+		return s, fmt.Errorf("not a passgen share")
+	}
+	body := strings.Join(parts[:5], "-")
+	sum := sha256.Sum256([]byte(body))
+	if parts[5] != hex.EncodeToString(sum[:shareCheckSize]) {
+		return s, fmt.Errorf("checksum mismatch — the share is corrupted")
+	}
+	s.set = parts[1]
+	m, n, ok := strings.Cut(parts[2], "of")
+	var err1, err2, err3 error
+	s.threshold, err1 = strconv.Atoi(m)
+	s.shares, err2 = strconv.Atoi(n)
+	s.index, err3 = strconv.Atoi(parts[3])
+	payload, err4 := hex.DecodeString(parts[4])
+	if !ok || err1 != nil || err2 != nil || err3 != nil || err4 != nil || s.index < 1 || s.index > 255 || len(payload) <= shareCheckSize {
+		return s, fmt.Errorf("malformed share")
+	}
+	s.payload = payload
+	return s, nil
+}
+
+// combineShares recovers the secret from at least threshold shares of one split.
+func combineShares(shares []share) ([]byte, error) {
+	if len(shares) == 0 {
+		return nil, fmt.Errorf("no shares given")
+	}
+	first := shares[0]
+	seen := make(map[int]bool)
+	for _, s := range shares {
+		if s.set != first.set || s.threshold != first.threshold || s.shares != first.shares {
+			return nil, fmt.Errorf("shares come from different splits (%s and %s)", first.set, s.set)
+		}
+		if len(s.payload) != len(first.payload) {
+			return nil, fmt.Errorf("share %d has a different length from share %d", s.index, first.index)
+		}
+		if seen[s.index] {
+			return nil, fmt.Errorf("share %d given twice", s.index)
+		}
+		seen[s.index] = true
+	}
+	if len(shares) < first.threshold {
+		return nil, fmt.Errorf("need %d shares, got %d", first.threshold, len(shares))
+	}
+	use := shares[:first.threshold]
+
+	// Lagrange interpolation at x = 0; in GF(256) subtraction is XOR
+	data := make([]byte, len(first.payload))
+	for i, si := range use {
+		xi := byte(si.index)
+		basis := byte(1)
+		for j, sj := range use {
+			if i != j {
+				xj := byte(sj.index)
+				basis = gfMul(basis, gfDiv(xj, xj^xi))
+			}
+		}
+		for b := range data {
+			data[b] ^= gfMul(si.payload[b], basis)
+		}
+	}
+	secret, check := data[:len(data)-shareCheckSize], data[len(data)-shareCheckSize:]
+	digest := sha256.Sum256(secret)
+	if !bytes.Equal(check, digest[:shareCheckSize]) {
+		return nil, fmt.Errorf("the recovered secret fails its checksum — one of the shares is wrong")
+	}
+	return secret, nil
+}
+
+// runCombine handles `passgen combine`, reading shares from the arguments
+// or, one per line, from stdin.
+func runCombine(args []string) {
+	lines := args
+	if len(lines) == 0 {
+		// Shares of a long -stdin secret are long lines: read them whole
+		r := bufio.NewReader(os.Stdin)
+		for {
+			line, err := r.ReadString('\n')
+			if line = strings.TrimSpace(line); line != "" {
+				lines = append(lines, line)
+			}
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+	}
+	var shares []share
+	for i, line := range lines {
+		s, err := parseShare(line)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: share %d: %v\n", i+1, err)
+			os.Exit(1)
+		}
+		shares = append(shares, s)
+	}
+	secret, err := combineShares(shares)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(string(secret))
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitEveryType(t *testing.T) {
+	for _, args := range [][]string{
+		{"-type", "random"},
+		{"-type", "segment"},
+		{"-type", "phrase"},
+		{"-type", "sentence"},
+		{"-type", "markov"},
+		{"-type", "bip39"},
+		{"-type", "bip39", "-words", "24"},
+		{"-type", "framework", "-preset", "django"},
+	} {
+		args = append([]string{"split", "-shares", "3", "-threshold", "2", "-no-copy", "-show-secret"}, args...)
+		out, errOut, err := runPassgen(t, "", args...)
+		if err != nil {
+			t.Errorf("passgen %s: %v\n%s", strings.Join(args, " "), err, errOut)
+			continue
+		}
+		shares := strings.Fields(out)
+		if len(shares) != 3 {
+			t.Errorf("passgen %s printed %d shares, want 3", strings.Join(args, " "), len(shares))
+			continue
+		}
+		_, secret, _ := strings.Cut(errOut, "Secret: ")
+		secret, _, _ = strings.Cut(secret, "\n")
+		got, errOut, err := runPassgen(t, "", "combine", shares[0], shares[2])
+		if err != nil {
+			t.Errorf("passgen combine: %v\n%s", err, errOut)
+		} else if got = strings.TrimSuffix(got, "\n"); got != secret {
+			t.Errorf("passgen %s: shares combine to %q, want %q", strings.Join(args, " "), got, secret)
+		}
+	}
+}
+
+func TestSplitHidesSecret(t *testing.T) {
+	// The test's stderr is a pipe, as in CI, so the secret stays hidden
+	out, errOut, err := runPassgen(t, "", "split", "-shares", "3", "-threshold", "2", "-no-copy")
+	if err != nil {
+		t.Fatalf("passgen split: %v\n%s", err, errOut)
+	}
+	if strings.Contains(errOut, "Secret:") || !strings.Contains(errOut, "Secret not shown") {
+		t.Errorf("passgen split wrote the secret to a redirected stderr:\n%s", errOut)
+	}
+	if n := len(strings.Fields(out)); n != 3 {
+		t.Errorf("passgen split printed %d shares, want 3", n)
+	}
+}
+
+func TestCombineLongShares(t *testing.T) {
+	// Shares of a 100 KB secret are lines far past bufio.Scanner's 64 KB
+	secret := strings.Repeat("correct horse battery staple ", 3500)
+	shares, errOut, err := runPassgen(t, secret, "split", "-stdin", "-shares", "3", "-threshold", "2")
+	if err != nil {
+		t.Fatalf("passgen split -stdin: %v\n%s", err, errOut)
+	}
+	got, errOut, err := runPassgen(t, shares, "combine")
+	if err != nil {
+		t.Fatalf("passgen combine: %v\n%s", err, errOut)
+	}
+	if got != secret+"\n" {
+		t.Errorf("combined %d bytes, want the %d-byte secret", len(got), len(secret))
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// ── Unicode character sets ───────────────────────────────────────────────────
+
+// runeRange returns every rune from lo to hi inclusive, minus any in skip.
+func runeRange(lo, hi rune, skip string) string {
+	var sb strings.Builder
+	for r := lo; r <= hi; r++ {
+		if !strings.ContainsRune(skip, r) {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// Optional -unicode sets. All are single precomposed code points, so every
+// password built from them is already in NFC.
+var unicodeSets = map[string]string{
+	"latin1":   runeRange('À', 'ÿ', "×÷"),                          // Latin-1 Supplement letters
+	"greek":    runeRange('Α', 'Ω', "΢") + runeRange('α', 'ω', ""), // basic Greek alphabet
+	"cyrillic": runeRange('А', 'я', "") + "Ёё",                     // Russian alphabet
+	"emoji":    runeRange(0x1F600, 0x1F64F, ""),                    // Emoticons block
+}
+
+var unicodeSetNames = []string{"latin1", "greek", "cyrillic", "emoji"}
+
+// Greek, Cyrillic and Latin-1 letters drawn the same as a Latin letter or digit;
+// -no-ambiguous removes them along with the ASCII lookalikes.
+const scriptConfusables = "ΑΒΕΖΗΙΚΜΝΟΡΤΥΧαικνορυχ" + "АВЕЗКМНОРСТУХаеорсух" + "ØøÐ"
+
+// parseUnicodeSets parses a comma-separated list of -unicode set names.
+func parseUnicodeSets(raw string) ([]string, error) {
+	var sets []string
+	for _, f := range strings.Split(raw, ",") {
+		name := strings.ToLower(strings.TrimSpace(f))
+		set, ok := unicodeSets[name]
+		if !ok {
+			return nil, fmt.Errorf("-unicode: unknown set %q — use %s", name, strings.Join(unicodeSetNames, ", "))
+		}
+		sets = append(sets, set)
+	}
+	return sets, nil
+}
+
+// ── Normalization ────────────────────────────────────────────────────────────
+
+// Hangul syllables decompose and compose arithmetically (Unicode 3.12).
+const (
+	hangulBase   = 0xAC00
+	hangulL      = 0x1100
+	hangulV      = 0x1161
+	hangulT      = 0x11A7
+	hangulTCount = 28
+	hangulNCount = 21 * hangulTCount
+	hangulCount  = 19 * hangulNCount
+)
+
+// nfc returns s in Normalization Form C, as UAX #15 defines it: every
+// character fully decomposed, each run of combining marks put in canonical
+// order, then each mark composed with the starter before it unless another
+// mark of the same or a lower class blocks it. Singletons and composition
+// exclusions stay decomposed.
+func nfc(s string) string {
+	var d []rune
+	for _, r := range s {
+		switch {
+		case r >= hangulBase && r < hangulBase+hangulCount:
+			i := r - hangulBase
+			d = append(d, hangulL+i/hangulNCount, hangulV+i%hangulNCount/hangulTCount)
+			if t := i % hangulTCount; t != 0 {
+				d = append(d, hangulT+t)
+			}
+		case canonicalDecomposition[r] != nil:
+			d = append(d, canonicalDecomposition[r]...)
+		default:
+			d = append(d, r)
+		}
+	}
+
+	// Canonical ordering: a stable sort of each run of non-starters by class
+	for i := 1; i < len(d); i++ {
+		for j := i; j > 0; j-- {
+			c := combiningClass[d[j]]
+			if c == 0 || combiningClass[d[j-1]] <= c {
+				break
+			}
+			d[j-1], d[j] = d[j], d[j-1]
+		}
+	}
+
+	out := d[:0]
+	starter := -1
+	var last uint8 // class of the last character kept since the starter
+	for _, r := range d {
+		c := combiningClass[r]
+		if starter >= 0 && (starter == len(out)-1 || last != 0 && last < c) {
+			if p, ok := compose(out[starter], r); ok {
+				out[starter] = p
+				continue
+			}
+		}
+		if c == 0 {
+			starter = len(out)
+		}
+		last = c
+		out = append(out, r)
+	}
+	return string(out)
+}
+
+// compose returns the primary composite of a followed by b, if there is one.
+func compose(a, b rune) (rune, bool) {
+	switch {
+	case a >= hangulL && a < hangulL+19 && b >= hangulV && b < hangulV+21:
+		return hangulBase + ((a-hangulL)*21+b-hangulV)*hangulTCount, true
+	case a >= hangulBase && a < hangulBase+hangulCount && (a-hangulBase)%hangulTCount == 0 &&
+		b > hangulT && b < hangulT+hangulTCount:
+		return a + b - hangulT, true
+	}
+	c, ok := compositions[[2]rune{a, b}]
+	return c, ok
+}
+
+// clusters splits s into user-perceived characters, so shuffling and
+// length limits never strand an accent on the wrong letter or break an
+// emoji apart. It follows the grapheme cluster rules of UAX #29 that
+// passwords meet: a rune joins the one before it when it is a combining or
+// spacing mark, a variation selector, an emoji skin-tone modifier or a
+// zero-width joiner, or follows a zero-width joiner; regional indicators
+// pair up into flags. Hangul jamo are left to nfc, which composes them.
+func clusters(s string) []string {
+	var out []string
+	joined, flag := false, false // the last rune was a ZWJ / began an unpaired flag
+	for _, r := range s {
+		regional := r >= 0x1F1E6 && r <= 0x1F1FF
+		extends := unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc) ||
+			r == zwj || r >= 0xFE00 && r <= 0xFE0F || r >= 0x1F3FB && r <= 0x1F3FF
+		if n := len(out); n > 0 && (extends || joined || regional && flag) {
+			out[n-1] += string(r)
+			flag = false
+		} else {
+			out = append(out, string(r))
+			flag = regional
+		}
+		joined = r == zwj
+	}
+	return out
+}
+
+const zwj = '\u200D' // zero-width joiner
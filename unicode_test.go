@@ -0,0 +1,141 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestNFC(t *testing.T) {
+	for _, tt := range []struct{ in, want string }{
+		{"\u1E0A", "\u1E0A"},
+		{"D\u0307", "\u1E0A"},
+		// Canonical reordering: the dot below (220) sorts before the dot above (230)
+		{"\u1E0A\u0323", "\u1E0C\u0307"},
+		{"D\u0307\u0323", "\u1E0C\u0307"},
+		{"D\u0323\u0307", "\u1E0C\u0307"},
+		{"\u00E2\u0323", "\u1EAD"},
+		{"\u00C5\u0328", "\u0104\u030A"},
+		{"\u03BF\u0314\u0301", "\u1F45"},
+		// Singletons
+		{"\u212B", "\u00C5"},
+		{"\u2126", "\u03A9"},
+		{"\u212A", "K"},
+		// Composition exclusions and non-starter decompositions
+		{"\u0958", "\u0915\u093C"},
+		{"\u0915\u093C", "\u0915\u093C"},
+		{"\u0344", "\u0308\u0301"},
+		// A mark of the same class blocks the one after it
+		{"a\u0308\u0301", "\u00E4\u0301"},
+		{"a\u0301\u0308", "\u00E1\u0308"},
+		{"e\u0301\u0301", "\u00E9\u0301"},
+		// A mark with no starter before it composes with nothing
+		{"\u0301\U00011F41", "\U00011F41\u0301"},
+		// Starters that compose with each other
+		{"\u0B47\u0B3E", "\u0B4B"},
+		{"\u1100\u1161", "\uAC00"},
+		{"\u1100\u1161\u11A8", "\uAC01"},
+		{"\uAC00\u11A8", "\uAC01"},
+		{"\uAC01", "\uAC01"},
+		{"\uAC01\u11A8", "\uAC01\u11A8"},
+	} {
+		if got := nfc(tt.in); got != tt.want {
+			t.Errorf("nfc(%+q) = %+q, want %+q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNFCStable(t *testing.T) {
+	for r := rune(0); r <= utf8.MaxRune; r++ {
+		if !utf8.ValidRune(r) {
+			continue
+		}
+		if once := nfc(string(r)); nfc(once) != once {
+			t.Errorf("nfc(nfc(%U)) = %+q, not %+q", r, nfc(once), once)
+		}
+	}
+}
+
+func TestClusters(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want []string
+	}{
+		{"abc", []string{"a", "b", "c"}},
+		{"q\u0307x", []string{"q\u0307", "x"}},
+		{"क\u093C\u093E", []string{"क\u093C\u093E"}},
+		{"\U0001F469\u200D\U0001F4BB!", []string{"\U0001F469\u200D\U0001F4BB", "!"}},
+		{"\U0001F44B\U0001F3FD❤\uFE0F", []string{"\U0001F44B\U0001F3FD", "❤\uFE0F"}},
+		{"\U0001F1EB\U0001F1F7\U0001F1E9\U0001F1EA\U0001F1EE", []string{"\U0001F1EB\U0001F1F7", "\U0001F1E9\U0001F1EA", "\U0001F1EE"}},
+	} {
+		if got := clusters(tt.in); !slices.Equal(got, tt.want) {
+			t.Errorf("clusters(%+q) = %+q, want %+q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// A word made of characters that are several code points each
+const clusterWord = "q\u0307\U0001F469\u200D\U0001F4BBx\u0323\u0304\U0001F1EB\U0001F1F7"
+
+func TestShuffleCharsKeepsClusters(t *testing.T) {
+	want := clusters(clusterWord)
+	slices.Sort(want)
+	cfg := PassphraseConfig{ShuffleChars: true, Separator: "-"}
+	for i := 0; i < 200; i++ {
+		p, err := finishPhrase(cfg, []string{clusterWord})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := clusters(p)
+		slices.Sort(got)
+		if !slices.Equal(got, want) {
+			t.Fatalf("shuffled %+q into %+q", clusterWord, p)
+		}
+	}
+}
+
+func TestMaxLengthCountsClusters(t *testing.T) {
+	n := len(clusters(clusterWord))
+	cfg := PassphraseConfig{Words: 1, Include: []string{clusterWord}, Separator: "-", MaxLength: n}
+	if p, err := generatePassphrase(cfg); err != nil || p != clusterWord {
+		t.Errorf("-max-length %d: got %+q, %v; want %+q", n, p, err, clusterWord)
+	}
+	cfg.MaxLength = n - 1
+	if p, err := generatePassphrase(cfg); err == nil {
+		t.Errorf("-max-length %d: got %+q, want an error", n-1, p)
+	}
+}
+
+func TestExcludeWholeCharacters(t *testing.T) {
+	for _, tt := range []struct{ s, exclude, want string }{
+		{"abcq", "q\u0307", "abcq"},
+		{"eéè", nfc("e\u0301"), "eè"},
+		{"ab\u00C5", nfc("\u212B"), "ab"},
+		{"x\U0001F469\u200D\U0001F4BB\U0001F469", "\U0001F469", "x\U0001F469\u200D\U0001F4BB"},
+	} {
+		if got := filterChars(tt.s, tt.exclude); got != tt.want {
+			t.Errorf("filterChars(%+q, %+q) = %+q, want %+q", tt.s, tt.exclude, got, tt.want)
+		}
+	}
+
+	cfg := RandomConfig{Length: 64, Charset: "eé", Exclude: nfc("e\u0301")}
+	p, err := generateRandom(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p != strings.Repeat("e", 64) {
+		t.Errorf("-charset eé -exclude é gave %q", p)
+	}
+}
+
+func TestCheckCharsetRejectsSequences(t *testing.T) {
+	for _, v := range []string{"ab\u0307", "\U0001F469\u200D\U0001F4BB", "❤\uFE0F", "\U0001F1EB\U0001F1F7"} {
+		if err := checkCharset("-charset", v); err == nil {
+			t.Errorf("checkCharset(%+q) accepted a character made of several code points", v)
+		}
+	}
+	if err := checkCharset("-charset", "é\U0001F600각"); err != nil {
+		t.Error(err)
+	}
+}
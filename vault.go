@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+)
+
+// ── Password manager imports ─────────────────────────────────────────────────
+//
+// -format bitwarden-json, 1password-csv and lastpass-csv print the passwords
+// as each manager's documented import file, one login per account.
+
+var vaultFormats = map[string]func(w io.Writer, accounts []Account, passwords []string) error{
+	"bitwarden-json": bitwardenJSON,
+	"1password-csv":  onePasswordCSV,
+	"lastpass-csv":   lastPassCSV,
+}
+
+type bitwardenURI struct {
+	Match *int   `json:"match"`
+	URI   string `json:"uri"`
+}
+
+type bitwardenLogin struct {
+	URIs     []bitwardenURI `json:"uris"`
+	Username *string        `json:"username"`
+	Password string         `json:"password"`
+	TOTP     *string        `json:"totp"`
+}
+
+type bitwardenItem struct {
+	Type     int            `json:"type"` // 1 is a login
+	Name     string         `json:"name"`
+	Notes    *string        `json:"notes"`
+	Favorite bool           `json:"favorite"`
+	Login    bitwardenLogin `json:"login"`
+}
+
+type bitwardenExport struct {
+	Encrypted bool            `json:"encrypted"`
+	Folders   []struct{}      `json:"folders"`
+	Items     []bitwardenItem `json:"items"`
+}
+
+// nullable is s, or JSON null when it's empty, as Bitwarden writes it.
+func nullable(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// bitwardenJSON is Bitwarden's unencrypted JSON export, which it imports as is.
+func bitwardenJSON(w io.Writer, accounts []Account, passwords []string) error {
+	export := bitwardenExport{Folders: []struct{}{}, Items: []bitwardenItem{}}
+	for i, a := range accounts {
+		item := bitwardenItem{Type: 1, Name: a.Title, Notes: nullable(a.Notes), Login: bitwardenLogin{
+			URIs:     []bitwardenURI{},
+			Username: nullable(a.Username),
+			Password: passwords[i],
+		}}
+		if a.URL != "" {
+			item.Login.URIs = append(item.Login.URIs, bitwardenURI{URI: a.URL})
+		}
+		export.Items = append(export.Items, item)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(export)
+}
+
+// onePasswordCSV has the columns 1Password's CSV import maps on its own.
+func onePasswordCSV(w io.Writer, accounts []Account, passwords []string) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"Title", "Website", "Username", "Password", "Notes"})
+	for i, a := range accounts {
+		cw.Write([]string{a.Title, a.URL, a.Username, passwords[i], a.Notes})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// lastPassCSV is LastPass's generic CSV: extra holds the notes, and the
+// entries go into no folder.
+func lastPassCSV(w io.Writer, accounts []Account, passwords []string) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"url", "username", "password", "totp", "extra", "name", "grouping", "fav"})
+	for i, a := range accounts {
+		cw.Write([]string{a.URL, a.Username, passwords[i], "", a.Notes, a.Title, "", "0"})
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"math/bits"
+)
+
+// ── yescrypt ($y$) ───────────────────────────────────────────────────────────
+//
+// The default hash of current Linux distributions, as libxcrypt computes it
+// with its default flags (read-write memory, 6 pwxform rounds, 12 KiB
+// S-boxes) and p = 1. yescrypt is scrypt with its BlockMix replaced by
+// pwxform, a small memory-hard transform over per-hash S-boxes.
+
+const (
+	yescryptFlags = 0xb6 // YESCRYPT_RW | ROUNDS_6 | GATHER_4 | SIMPLE_2 | SBOX_12K
+
+	pwxGather = 4
+	pwxSimple = 2
+	pwxRounds = 6
+	pwxWords  = pwxGather * pwxSimple * 2 // uint32s per pwxform block
+	sboxPairs = (1 << 8) * pwxSimple      // 64-bit entries in each of S0, S1, S2
+)
+
+// yescryptEncode is yescrypt's base64: crypt's alphabet, little-endian
+// groups of up to three bytes.
+func yescryptEncode(src []byte) string {
+	var out []byte
+	for i := 0; i < len(src); {
+		var v, n uint32
+		for ; n < 24 && i < len(src); n += 8 {
+			v |= uint32(src[i]) << n
+			i++
+		}
+		for b := uint32(0); b < n; b += 6 {
+			out = append(out, cryptAlphabet[v&0x3f])
+			v >>= 6
+		}
+	}
+	return string(out)
+}
+
+// yescryptHash returns the $y$ hash of password with N = 2^ln and block
+// size r (at most 48, so each fits one setting character).
+func yescryptHash(password, salt []byte, ln, r int) string {
+	// $y$ setting: flavour, log2 N and r, each one character
+	setting := "$y$" + string([]byte{cryptAlphabet[2+yescryptFlags>>2], cryptAlphabet[ln-1], cryptAlphabet[r-1]})
+	key := yescryptKDF(password, salt, 1<<ln, r)
+	return setting + "$" + yescryptEncode(salt) + "$" + yescryptEncode(key)
+}
+
+// yescryptKDF derives the 32-byte hash. Large settings first prehash the
+// password with N/64, as libxcrypt does.
+func yescryptKDF(password, salt []byte, n uint64, r int) []byte {
+	if n >= 0x100 && n*uint64(r) >= 0x20000 {
+		password = yescryptBody(password, salt, n>>6, r, true)
+	}
+	return yescryptBody(password, salt, n, r, false)
+}
+
+func yescryptBody(password, salt []byte, n uint64, r int, prehash bool) []byte {
+	label := "yescrypt"
+	if prehash {
+		label = "yescrypt-prehash"
+	}
+	mac := hmac.New(sha256.New, []byte(label))
+	mac.Write(password)
+	passwd := mac.Sum(nil)
+
+	b := pbkdf2Key(sha256.New, passwd, salt, 1, 128*r)
+	copy(passwd, b)
+	yescryptSmix(b, r, n, passwd)
+
+	dk := pbkdf2Key(sha256.New, passwd, b, 1, 32)
+	if prehash {
+		return dk
+	}
+	// The SCRAM-style ending: StoredKey = H(HMAC(dk, "Client Key"))
+	mac = hmac.New(sha256.New, dk)
+	mac.Write([]byte("Client Key"))
+	stored := sha256.Sum256(mac.Sum(nil))
+	return stored[:]
+}
+
+// pwxform carries the S-boxes and write position between pwxform calls.
+type pwxform struct {
+	s0, s1, s2 []uint32
+	w          int
+}
+
+// yescryptSmix runs the memory-hard phase on b in place, and rekeys passwd
+// with the block it starts from.
+func yescryptSmix(b []byte, r int, n uint64, passwd []byte) {
+	// Nloop for t = 0: a third of N, rounded up to even
+	nloop := (n + 2) / 3
+	nloop += nloop & 1
+
+	// The S-boxes come from scrypt's SMix over the first 128 bytes
+	s := make([]uint32, 3*sboxPairs*2)
+	yescryptSmix1(b[:128], 1, uint64(len(s)/32), s, nil)
+	ctx := &pwxform{s0: s[2*sboxPairs*2:], s1: s[sboxPairs*2 : 2*sboxPairs*2], s2: s[:sboxPairs*2]}
+
+	mac := hmac.New(sha256.New, b[len(b)-64:])
+	mac.Write(passwd)
+	copy(passwd, mac.Sum(nil))
+
+	v := make([]uint32, n*uint64(32*r))
+	yescryptSmix1(b, r, n, v, ctx)
+	yescryptSmix2(b, r, n, nloop, v, ctx)
+}
+
+// loadBlocks reads b into x, storing each 64-byte block in the shuffled
+// word order the reference implementation uses.
+func loadBlocks(x []uint32, b []byte) {
+	for k := 0; k < len(b)/64; k++ {
+		for i := 0; i < 16; i++ {
+			x[k*16+i] = binary.LittleEndian.Uint32(b[k*64+i*5%16*4:])
+		}
+	}
+}
+
+func storeBlocks(b []byte, x []uint32) {
+	for k := 0; k < len(b)/64; k++ {
+		for i := 0; i < 16; i++ {
+			binary.LittleEndian.PutUint32(b[k*64+i*5%16*4:], x[k*16+i])
+		}
+	}
+}
+
+// yescryptSmix1 fills v with n successive states of b. Without ctx this is
+// scrypt's first loop; with it, each state also mixes in an earlier one.
+func yescryptSmix1(b []byte, r int, n uint64, v []uint32, ctx *pwxform) {
+	size := 32 * r
+	x := make([]uint32, size)
+	loadBlocks(x, b)
+	for i := uint64(0); i < n; i++ {
+		copy(v[i*uint64(size):], x)
+		if ctx != nil && i > 1 {
+			j := wrap(integerify(x, r), i)
+			xorWords(x, v[j*uint64(size):(j+1)*uint64(size)])
+		}
+		yescryptBlockMix(x, r, ctx)
+	}
+	storeBlocks(b, x)
+}
+
+// yescryptSmix2 is scrypt's second loop, writing each state back into v.
+func yescryptSmix2(b []byte, r int, n, nloop uint64, v []uint32, ctx *pwxform) {
+	size := uint64(32 * r)
+	x := make([]uint32, size)
+	loadBlocks(x, b)
+	for i := uint64(0); i < nloop; i++ {
+		j := integerify(x, r) & (n - 1)
+		vj := v[j*size : (j+1)*size]
+		xorWords(x, vj)
+		copy(vj, x)
+		yescryptBlockMix(x, r, ctx)
+	}
+	storeBlocks(b, x)
+}
+
+func yescryptBlockMix(x []uint32, r int, ctx *pwxform) {
+	if ctx == nil {
+		blockMixSalsa8(x, r)
+		return
+	}
+	// Every 64-byte block goes through pwxform, chained; the last then
+	// through Salsa20/2
+	blocks := 2 * r
+	t := make([]uint32, pwxWords)
+	copy(t, x[(blocks-1)*pwxWords:])
+	for i := 0; i < blocks; i++ {
+		if blocks > 1 {
+			xorWords(t, x[i*pwxWords:(i+1)*pwxWords])
+		}
+		ctx.apply(t)
+		copy(x[i*pwxWords:], t)
+	}
+	salsaShuffled(x[(blocks-1)*16:blocks*16], 2)
+}
+
+// blockMixSalsa8 is scrypt's BlockMix on shuffled words.
+func blockMixSalsa8(x []uint32, r int) {
+	t := make([]uint32, 16)
+	y := make([]uint32, len(x))
+	copy(t, x[(2*r-1)*16:])
+	for i := 0; i < 2*r; i++ {
+		xorWords(t, x[i*16:(i+1)*16])
+		salsaShuffled(t, 8)
+		copy(y[i*16:], t)
+	}
+	for i := 0; i < r; i++ {
+		copy(x[i*16:], y[2*i*16:(2*i+1)*16])
+		copy(x[(i+r)*16:], y[(2*i+1)*16:(2*i+2)*16])
+	}
+}
+
+// apply is pwxform on one block: rounds of 64-bit multiply, add and XOR
+// with S-box entries picked by the data, writing results back into S2.
+func (c *pwxform) apply(x []uint32) {
+	for round := 0; round < pwxRounds; round++ {
+		for j := 0; j < pwxGather; j++ {
+			p0 := int(x[j*4]&0xff0) >> 2 // byte offset / 4: a uint32 index
+			p1 := int(x[j*4+1]&0xff0) >> 2
+			for k := 0; k < pwxSimple; k++ {
+				lo, hi := x[j*4+k*2], x[j*4+k*2+1]
+				s0 := uint64(c.s0[p0+k*2+1])<<32 | uint64(c.s0[p0+k*2])
+				s1 := uint64(c.s1[p1+k*2+1])<<32 | uint64(c.s1[p1+k*2])
+				v := (uint64(hi)*uint64(lo) + s0) ^ s1
+				x[j*4+k*2], x[j*4+k*2+1] = uint32(v), uint32(v>>32)
+				if round != 0 && round != pwxRounds-1 {
+					c.s2[c.w*2], c.s2[c.w*2+1] = uint32(v), uint32(v>>32)
+					c.w++
+				}
+			}
+		}
+	}
+	c.s0, c.s1, c.s2 = c.s2, c.s0, c.s1
+	c.w &= sboxPairs - 1
+}
+
+// salsaShuffled applies the Salsa20 core to a block stored in shuffled order.
+func salsaShuffled(b []uint32, rounds int) {
+	var x [16]uint32
+	for i := range x {
+		x[i*5%16] = b[i]
+	}
+	salsa20(&x, rounds)
+	for i := range x {
+		b[i] = x[i*5%16]
+	}
+}
+
+// integerify reads words 0 and 1 of the last 64-byte block, which the
+// shuffle stores at positions 0 and 13.
+func integerify(x []uint32, r int) uint64 {
+	last := x[(2*r-1)*16:]
+	return uint64(last[13])<<32 | uint64(last[0])
+}
+
+// wrap maps x into the last power-of-two-sized window below i.
+func wrap(x, i uint64) uint64 {
+	n := uint64(1) << (63 - bits.LeadingZeros64(i))
+	return x&(n-1) + i - n
+}
+
+func xorWords(dst, src []uint32) {
+	for i := range dst {
+		dst[i] ^= src[i]
+	}
+}